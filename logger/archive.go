@@ -0,0 +1,333 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	archiveExt        = ".ndjson"
+	archivePartialExt = ".partial"
+	archiveBadExt     = ".bad"
+
+	// archiveIdleRotate is how long a segment can go without writes before
+	// it is completed, so that a running logger never leaves a partial
+	// segment untouched for long.
+	archiveIdleRotate = time.Minute
+	// archiveStaleAge is the age after which Replay considers a partial
+	// segment abandoned by a process which did not close its logger.
+	archiveStaleAge = 10 * time.Minute
+)
+
+// archive writes payloads as newline-delimited JSON into segment files
+// under a directory. The segment being written has the archivePartialExt
+// suffix, which is removed once the segment is complete so that Replay
+// only picks up finished segments, or partial segments left stale by a
+// process which exited without closing its logger. Payloads for different ingestion keys,
+// as set by Options.Router, are written to separate segments named after
+// a hash of the key.
+type archive struct {
+	dir          string
+	segmentBytes int
+
+	mu       sync.Mutex
+	segments map[string]*segment
+}
+
+type segment struct {
+	file      *os.File
+	written   int
+	lastWrite time.Time
+}
+
+func newArchive(dir string, segmentBytes int) *archive {
+	return &archive{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		segments:     make(map[string]*segment),
+	}
+}
+
+// archiveKeyID identifies the ingestion key in segment names without
+// storing the key itself.
+func archiveKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// write appends the payload to the current segment of its ingestion key.
+// The key is left out so it is never stored on disk; it is provided to
+// Replay.
+func (a *archive) write(payload Payload) error {
+	key := payload.APIKey
+	payload.APIKey = ""
+	pbytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := a.segments[key]
+	if s == nil {
+		err := os.MkdirAll(a.dir, 0755)
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("logdna-%s-%d%s%s", archiveKeyID(key), time.Now().UnixNano(), archiveExt, archivePartialExt)
+		file, err := os.OpenFile(filepath.Join(a.dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+		s = &segment{file: file}
+		a.segments[key] = s
+	}
+
+	n, err := s.file.Write(append(pbytes, '\n'))
+	s.written += n
+	s.lastWrite = time.Now()
+	if err != nil {
+		return err
+	}
+
+	if s.written >= a.segmentBytes {
+		return a.rotate(key)
+	}
+	return nil
+}
+
+// rotate completes the current segment of the key.
+func (a *archive) rotate(key string) error {
+	s := a.segments[key]
+	delete(a.segments, key)
+
+	name := s.file.Name()
+	err := s.file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(name, strings.TrimSuffix(name, archivePartialExt))
+}
+
+// rotateIdle completes the segments which were not written to within
+// archiveIdleRotate.
+func (a *archive) rotateIdle() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	cutoff := time.Now().Add(-archiveIdleRotate)
+	for key, s := range a.segments {
+		if !s.lastWrite.Before(cutoff) {
+			continue
+		}
+		if rerr := a.rotate(key); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+func (a *archive) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var err error
+	for key := range a.segments {
+		if rerr := a.rotate(key); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// Replay uploads the payloads archived in dir by a logger configured with
+// Options.ArchiveDir for the provided ingestion key. Payloads archived for
+// other keys by Options.Router are left for a Replay with their key. Each payload is
+// retried up to Options.MaxRetries times after network errors, server
+// errors and rate limiting; any other rejection fails immediately. Segments are deleted once all of
+// their payloads are sent; when a payload cannot be sent, the segment is
+// rewritten with only the remaining payloads and the error is returned.
+// Partial segments which were not written to within archiveStaleAge, left
+// by a process which exited without closing its logger, are completed and
+// replayed as well.
+// Lines which are not valid payloads are moved to a file with the
+// archiveBadExt suffix next to their segment, and reported by an error
+// once every segment has been replayed.
+func Replay(dir string, key string, options Options) error {
+	err := options.validate()
+	if err != nil {
+		return err
+	}
+	options.setDefaults()
+
+	pattern := filepath.Join(dir, "logdna-"+archiveKeyID(key)+"-*"+archiveExt)
+	err = completeStale(pattern + archivePartialExt)
+	if err != nil {
+		return err
+	}
+
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	bad := 0
+	for _, name := range names {
+		n, err := replaySegment(name, key, options)
+		bad += n
+		if err != nil {
+			return err
+		}
+	}
+
+	if bad > 0 {
+		return fmt.Errorf("%d malformed archived payloads moved to %s files", bad, archiveBadExt)
+	}
+	return nil
+}
+
+// completeStale removes the partial suffix from the segments matching the
+// pattern which were last written before archiveStaleAge.
+func completeStale(pattern string) error {
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-archiveStaleAge)
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		err = os.Rename(name, strings.TrimSuffix(name, archivePartialExt))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaySegment sends the payloads of a segment, returning the number of
+// malformed lines moved aside.
+func replaySegment(name string, key string, options Options) (int, error) {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var payloads [][]byte
+	var bad [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var payload map[string]json.RawMessage
+		if json.Unmarshal(line, &payload) != nil || payload == nil {
+			bad = append(bad, line)
+			continue
+		}
+		payloads = append(payloads, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	// malformed lines would fail every Replay, so they are moved aside
+	// before sending anything
+	if len(bad) > 0 {
+		err := appendLines(name+archiveBadExt, bad)
+		if err != nil {
+			return 0, err
+		}
+		err = writeLines(name, payloads)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for i, pbytes := range payloads {
+		err := replayPayload(pbytes, key, options)
+		if err != nil {
+			// keep the payloads which were not sent for the next Replay
+			if werr := writeLines(name, payloads[i:]); werr != nil {
+				return len(bad), werr
+			}
+			return len(bad), err
+		}
+	}
+
+	return len(bad), os.Remove(name)
+}
+
+func writeLines(name string, lines [][]byte) error {
+	return ioutil.WriteFile(name, append(bytes.Join(lines, []byte("\n")), '\n'), 0644)
+}
+
+func appendLines(name string, lines [][]byte) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(bytes.Join(lines, []byte("\n")), '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func replayPayload(pbytes []byte, key string, options Options) error {
+	var payload map[string]json.RawMessage
+	err := json.Unmarshal(pbytes, &payload)
+	if err != nil {
+		return err
+	}
+
+	payload["apikey"], err = json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	pbytes, err = json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = postBytes(pbytes, key, options)
+		if err == nil || !retryable(err) || attempt >= options.MaxRetries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
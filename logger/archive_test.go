@@ -0,0 +1,310 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func archiveTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "logdna-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestArchive_Logger(t *testing.T) {
+	dir := archiveTestDir(t)
+	defer os.RemoveAll(dir)
+
+	o := Options{
+		ArchiveDir:   dir,
+		MaxBufferLen: 2,
+		Hostname:     "foo",
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Log("testing0")
+	l.Log("testing1")
+	l.Log("testing2")
+	l.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+	if assert.Equal(t, 1, len(names)) {
+		content, err := ioutil.ReadFile(names[0])
+		assert.Equal(t, nil, err)
+		assert.NotContains(t, string(content), "abc123")
+
+		payloads := strings.Split(strings.TrimSpace(string(content)), "\n")
+		assert.Equal(t, 2, len(payloads))
+
+		lines := 0
+		for _, p := range payloads {
+			body := make(map[string](interface{}))
+			json.Unmarshal([]byte(p), &body)
+			assert.Equal(t, "foo", body["hostname"])
+			lines += len(body["lines"].([]interface{}))
+		}
+		assert.Equal(t, 3, lines)
+	}
+}
+
+func TestArchive_SegmentRotation(t *testing.T) {
+	dir := archiveTestDir(t)
+	defer os.RemoveAll(dir)
+
+	a := newArchive(dir, 1)
+	assert.Equal(t, nil, a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "testing0"}}}))
+	assert.Equal(t, nil, a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "testing1"}}}))
+	assert.Equal(t, nil, a.close())
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+	assert.Equal(t, 2, len(names))
+}
+
+func TestArchive_Router(t *testing.T) {
+	dir := archiveTestDir(t)
+	defer os.RemoveAll(dir)
+
+	o := Options{
+		ArchiveDir: dir,
+		Router: func(msg Message) Route {
+			if msg.Body == "acme" {
+				return Route{Key: "acme-key"}
+			}
+			return Route{}
+		},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+	l.Log("default")
+	l.Log("acme")
+	l.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+	assert.Equal(t, 2, len(names))
+
+	for _, key := range []string{"abc123", "acme-key"} {
+		var bodies []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload Payload
+			json.NewDecoder(r.Body).Decode(&payload)
+			assert.Equal(t, key, payload.APIKey)
+			for _, line := range payload.Lines {
+				bodies = append(bodies, line.Body)
+			}
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+
+		// each key only replays its own lines
+		assert.Equal(t, nil, Replay(dir, key, Options{IngestURL: ts.URL}))
+		ts.Close()
+		if key == "abc123" {
+			assert.Equal(t, []string{"default"}, bodies)
+		} else {
+			assert.Equal(t, []string{"acme"}, bodies)
+		}
+	}
+}
+
+func TestArchive_Replay(t *testing.T) {
+	t.Run("Base", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		a := newArchive(dir, 1)
+		a.write(Payload{APIKey: "abc123", Tags: "archived", Lines: []Line{{Body: "testing0"}}})
+		a.write(Payload{APIKey: "abc123", Tags: "archived", Lines: []Line{{Body: "testing1"}}})
+		a.close()
+
+		var mu sync.Mutex
+		var bodies []map[string]interface{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := make(map[string](interface{}))
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "abc123", r.Header.Get("apikey"))
+
+			mu.Lock()
+			bodies = append(bodies, body)
+			mu.Unlock()
+
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		err := Replay(dir, "abc123", Options{IngestURL: ts.URL})
+		assert.Equal(t, nil, err)
+
+		if assert.Equal(t, 2, len(bodies)) {
+			assert.Equal(t, "abc123", bodies[0]["apikey"])
+			assert.Equal(t, "archived", bodies[0]["tags"])
+		}
+
+		names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+		assert.Equal(t, 0, len(names))
+	})
+
+	t.Run("Retries and keeps unsent payloads", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		a := newArchive(dir, defaultArchiveSegmentBytes)
+		a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "testing0"}}})
+		a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "testing1"}}})
+		a.close()
+
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls > 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		err := Replay(dir, "abc123", Options{
+			IngestURL:    ts.URL,
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 4, calls)
+
+		names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+		if assert.Equal(t, 1, len(names)) {
+			content, _ := ioutil.ReadFile(names[0])
+			assert.NotContains(t, string(content), "testing0")
+			assert.Contains(t, string(content), "testing1")
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		a := newArchive(dir, defaultArchiveSegmentBytes)
+		a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "testing0"}}})
+		a.close()
+
+		calls := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad key"})
+		}))
+		defer ts.Close()
+
+		err := Replay(dir, "abc123", Options{
+			IngestURL:    ts.URL,
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		})
+		assert.EqualError(t, err, "Request rejected: 401")
+		assert.Equal(t, 1, calls)
+
+		// the archive is kept for a Replay with the right key
+		names, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+		assert.Equal(t, 1, len(names))
+	})
+
+	t.Run("Malformed lines", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		name := filepath.Join(dir, "logdna-"+archiveKeyID("abc123")+"-1"+archiveExt)
+		content := `{"lines":[{"line":"testing0"}]}` + "\n{truncated\n" + `{"lines":[{"line":"testing1"}]}` + "\n"
+		assert.Equal(t, nil, ioutil.WriteFile(name, []byte(content), 0644))
+
+		var mu sync.Mutex
+		var calls int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		err := Replay(dir, "abc123", Options{IngestURL: ts.URL})
+		assert.EqualError(t, err, "1 malformed archived payloads moved to .bad files")
+		assert.Equal(t, 2, calls)
+
+		_, err = os.Stat(name)
+		assert.True(t, os.IsNotExist(err))
+		bad, err := ioutil.ReadFile(name + archiveBadExt)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "{truncated\n", string(bad))
+
+		// nothing is left to replay
+		assert.Equal(t, nil, Replay(dir, "abc123", Options{IngestURL: ts.URL}))
+	})
+
+	t.Run("Stale partial segments", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		prefix := filepath.Join(dir, "logdna-"+archiveKeyID("abc123"))
+		stale := prefix + "-1" + archiveExt + archivePartialExt
+		fresh := prefix + "-2" + archiveExt + archivePartialExt
+		assert.Equal(t, nil, ioutil.WriteFile(stale, []byte(`{"lines":[{"line":"stale"}]}`+"\n"), 0644))
+		assert.Equal(t, nil, ioutil.WriteFile(fresh, []byte(`{"lines":[{"line":"fresh"}]}`+"\n"), 0644))
+		old := time.Now().Add(-2 * archiveStaleAge)
+		assert.Equal(t, nil, os.Chtimes(stale, old, old))
+
+		var mu sync.Mutex
+		var lines []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Lines []Line `json:"lines"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+
+			mu.Lock()
+			for _, line := range body.Lines {
+				lines = append(lines, line.Body)
+			}
+			mu.Unlock()
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		assert.Equal(t, nil, Replay(dir, "abc123", Options{IngestURL: ts.URL}))
+		assert.Equal(t, []string{"stale"}, lines)
+
+		// the segment which may still be written to is left alone
+		names, _ := filepath.Glob(filepath.Join(dir, "*"))
+		assert.Equal(t, []string{fresh}, names)
+	})
+
+	t.Run("Idle segments are completed", func(t *testing.T) {
+		dir := archiveTestDir(t)
+		defer os.RemoveAll(dir)
+
+		a := newArchive(dir, defaultArchiveSegmentBytes)
+		a.write(Payload{APIKey: "abc123", Lines: []Line{{Body: "idle"}}})
+		a.write(Payload{APIKey: "def456", Lines: []Line{{Body: "active"}}})
+		a.segments["abc123"].lastWrite = time.Now().Add(-2 * archiveIdleRotate)
+		assert.Equal(t, nil, a.rotateIdle())
+
+		complete, _ := filepath.Glob(filepath.Join(dir, "*"+archiveExt))
+		partial, _ := filepath.Glob(filepath.Join(dir, "*"+archivePartialExt))
+		assert.Equal(t, 1, len(complete))
+		assert.Equal(t, 1, len(partial))
+		a.close()
+	})
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AtomicLevel is a minimum level which can be changed safely while
+// messages are being logged. A Logger and all of its children share one
+// AtomicLevel, so changing it affects the whole tree.
+type AtomicLevel struct {
+	level int32
+
+	mu       sync.Mutex
+	revert   *time.Timer
+	base     Level
+	revertAt time.Time
+}
+
+// NewAtomicLevel creates an AtomicLevel set to the given level.
+func NewAtomicLevel(level Level) *AtomicLevel {
+	return &AtomicLevel{level: int32(level)}
+}
+
+// Level returns the current minimum level.
+func (a *AtomicLevel) Level() Level {
+	return Level(atomic.LoadInt32(&a.level))
+}
+
+// SetLevel changes the minimum level, cancelling any pending revert.
+func (a *AtomicLevel) SetLevel(level Level) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopRevert()
+	atomic.StoreInt32(&a.level, int32(level))
+}
+
+// SetLevelFor changes the minimum level for the given duration, after which
+// it reverts to the level in effect before any temporary change.
+func (a *AtomicLevel) SetLevelFor(level Level, d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.revert == nil {
+		a.base = a.Level()
+	}
+	a.stopRevert()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		// a later change replaced this timer
+		if a.revert != timer {
+			return
+		}
+		a.revert = nil
+		a.revertAt = time.Time{}
+		atomic.StoreInt32(&a.level, int32(a.base))
+	})
+	a.revert = timer
+	a.revertAt = time.Now().Add(d)
+	atomic.StoreInt32(&a.level, int32(level))
+}
+
+func (a *AtomicLevel) stopRevert() {
+	if a.revert != nil {
+		a.revert.Stop()
+		a.revert = nil
+		a.revertAt = time.Time{}
+	}
+}
+
+type levelRequest struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration,omitempty"`
+}
+
+type levelResponse struct {
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// ServeHTTP reports the current level on GET requests, and changes it on
+// PUT requests with a JSON body such as {"level": "debug"}. An optional
+// "duration", such as "10m", reverts the change automatically.
+func (a *AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := a.update(r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(levelResponse{Error: err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(levelResponse{Error: "method not allowed"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(a.state())
+}
+
+func (a *AtomicLevel) update(r *http.Request) error {
+	var req levelRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return err
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+
+	if req.Duration == "" {
+		a.SetLevel(level)
+		return nil
+	}
+
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		return err
+	}
+	a.SetLevelFor(level, d)
+	return nil
+}
+
+func (a *AtomicLevel) state() levelResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	resp := levelResponse{}
+	if level := a.Level(); level != 0 {
+		resp.Level = level.String()
+	}
+	if !a.revertAt.IsZero() {
+		revertAt := a.revertAt
+		resp.RevertAt = &revertAt
+	}
+	return resp
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicLevel_SetLevel(t *testing.T) {
+	a := NewAtomicLevel(InfoLevel)
+	assert.Equal(t, InfoLevel, a.Level())
+
+	a.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, a.Level())
+}
+
+func TestAtomicLevel_SetLevelFor(t *testing.T) {
+	a := NewAtomicLevel(InfoLevel)
+
+	a.SetLevelFor(DebugLevel, 50*time.Millisecond)
+	a.SetLevelFor(TraceLevel, 50*time.Millisecond)
+	assert.Equal(t, TraceLevel, a.Level())
+
+	// reverts to the level in effect before the temporary changes
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, InfoLevel, a.Level())
+
+	t.Run("Cancelled by SetLevel", func(t *testing.T) {
+		a.SetLevelFor(DebugLevel, 20*time.Millisecond)
+		a.SetLevel(ErrorLevel)
+
+		time.Sleep(40 * time.Millisecond)
+		assert.Equal(t, ErrorLevel, a.Level())
+	})
+}
+
+func TestAtomicLevel_ServeHTTP(t *testing.T) {
+	a := NewAtomicLevel(InfoLevel)
+	ts := httptest.NewServer(a)
+	defer ts.Close()
+
+	do := func(method string, body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, ts.URL, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		decoded := make(map[string]interface{})
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp.StatusCode, decoded
+	}
+
+	status, body := do("GET", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "info", body["level"])
+
+	status, body = do("PUT", `{"level": "DEBUG"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "debug", body["level"])
+	assert.Equal(t, DebugLevel, a.Level())
+
+	status, body = do("PUT", `{"level": "trace", "duration": "1h"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "trace", body["level"])
+	assert.NotEmpty(t, body["revert_at"])
+
+	status, body = do("PUT", `{"level": "verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, body["error"])
+	assert.Equal(t, TraceLevel, a.Level())
+
+	status, _ = do("POST", `{"level": "info"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	var mu sync.Mutex
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		lines = append(lines, body["lines"].([]interface{})...)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, MinLevel: InfoLevel}, "abc123")
+	assert.Equal(t, nil, err)
+
+	child := l.WithApp("child")
+	assert.Equal(t, InfoLevel, child.Level())
+
+	// concurrent logging while the level changes must be race-free
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				child.Info("testing")
+			}
+		}()
+	}
+	l.SetLevel(ErrorLevel)
+	wg.Wait()
+
+	assert.Equal(t, ErrorLevel, child.Level())
+
+	child.Info("dropped")
+	child.Error("kept")
+	l.Close()
+
+	bodies := make(map[interface{}]int)
+	for _, line := range lines {
+		bodies[line.(map[string]interface{})["line"]]++
+	}
+	assert.Equal(t, 0, bodies["dropped"])
+	assert.Equal(t, 1, bodies["kept"])
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// callerField is the metadata field holding the caller information.
+const callerField = "caller"
+
+// maxCallerDepth bounds the frames inspected to find the caller.
+const maxCallerDepth = 32
+
+// libraryDir is the directory of this package, whose frames are skipped
+// when looking for the caller.
+var libraryDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+func isLibraryFrame(frame runtime.Frame) bool {
+	return filepath.Dir(frame.File) == libraryDir && !strings.HasSuffix(frame.File, "_test.go")
+}
+
+// callerFrame returns the frame of the code which called into the library,
+// skipping skip further frames so that logging wrappers can report their
+// own callers.
+func callerFrame(skip int) (runtime.Frame, bool) {
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isLibraryFrame(frame) {
+			if skip == 0 {
+				return frame, true
+			}
+			skip--
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// shortPath trims a file path to its directory and file name.
+func shortPath(file string) string {
+	dir, name := filepath.Split(file)
+	return filepath.Join(filepath.Base(dir), name)
+}
+
+// addCaller records the caller's file, line and function in the message's
+// metadata, and optionally as the file of the line.
+func (l *Logger) addCaller(msg *Message) {
+	frame, ok := callerFrame(l.Options.CallerSkip)
+	if !ok {
+		return
+	}
+
+	file := shortPath(frame.File)
+	msg.AddFields(Fields{callerField: Fields{
+		"file":     file,
+		"line":     frame.Line,
+		"function": frame.Function,
+	}})
+	if l.Options.CallerAsFile {
+		msg.file = file
+	}
+}
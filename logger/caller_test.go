@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogger returns a logger whose messages are recorded and dropped
+// before reaching the transport.
+func captureLogger(t testing.TB, o Options) (*Logger, *[]Message) {
+	var msgs []Message
+	o.Hooks = append(o.Hooks, func(msg *Message) bool {
+		msgs = append(msgs, *msg)
+		return false
+	})
+
+	l, err := NewLogger(o, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, &msgs
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func logFromWrapper(l *Logger) {
+	l.Info("wrapped")
+}
+
+func TestLogger_ReportCaller(t *testing.T) {
+	l, msgs := captureLogger(t, Options{ReportCaller: true, CallerAsFile: true})
+	defer l.Close()
+
+	testCases := []struct {
+		label string
+		fn    func() int
+	}{
+		{"Log", func() int { l.Log("testing"); return currentLine() }},
+		{"Info", func() int { l.Info("testing"); return currentLine() }},
+		{"Infof", func() int { l.Infof("%s", "testing"); return currentLine() }},
+		{"Infow", func() int { l.Infow("testing", "key", "value"); return currentLine() }},
+		{"InfoContext", func() int { l.InfoContext(context.Background(), "testing"); return currentLine() }},
+		{"Child", func() int { l.WithApp("child").Info("testing"); return currentLine() }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			*msgs = nil
+			line := tc.fn()
+
+			if assert.Equal(t, 1, len(*msgs)) {
+				msg := (*msgs)[0]
+				caller := msg.Options.Fields[callerField].(Fields)
+				assert.Equal(t, "logger/caller_test.go", caller["file"])
+				assert.Equal(t, line, caller["line"])
+				assert.Contains(t, caller["function"], "TestLogger_ReportCaller")
+				assert.Equal(t, "logger/caller_test.go", msg.file)
+			}
+		})
+	}
+}
+
+func TestLogger_CallerSkip(t *testing.T) {
+	l, msgs := captureLogger(t, Options{ReportCaller: true, CallerSkip: 1})
+	defer l.Close()
+
+	logFromWrapper(l)
+	line := currentLine() - 1
+
+	if assert.Equal(t, 1, len(*msgs)) {
+		caller := (*msgs)[0].Options.Fields[callerField].(Fields)
+		assert.Equal(t, line, caller["line"])
+		assert.Contains(t, caller["function"], "TestLogger_CallerSkip")
+	}
+}
+
+func TestLogger_ReportCallerDisabled(t *testing.T) {
+	l, msgs := captureLogger(t, Options{})
+	defer l.Close()
+
+	l.Info("testing")
+
+	if assert.Equal(t, 1, len(*msgs)) {
+		assert.Nil(t, (*msgs)[0].Options.Fields)
+		assert.Equal(t, "", (*msgs)[0].file)
+	}
+}
+
+func BenchmarkLogger_Caller(b *testing.B) {
+	drop := func(msg *Message) bool { return false }
+
+	for _, bc := range []struct {
+		label   string
+		options Options
+	}{
+		{"Disabled", Options{Hooks: []Hook{drop}}},
+		{"Enabled", Options{Hooks: []Hook{drop}, ReportCaller: true}},
+	} {
+		b.Run(bc.label, func(b *testing.B) {
+			l, err := NewLogger(bc.options, "abc123")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer l.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Info("testing")
+			}
+		})
+	}
+}
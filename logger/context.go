@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextExtractor returns the fields to add to a message from the values
+// stored in a context.Context, such as request or user IDs.
+type ContextExtractor func(ctx context.Context) Fields
+
+type contextKey int
+
+const (
+	loggerContextKey contextKey = iota
+	fieldsContextKey
+)
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []ContextExtractor
+)
+
+// RegisterContextExtractor adds an extractor which is applied to the
+// context of every message logged with a context-aware method. Extractors
+// are applied in the order they are registered, with later extractors
+// taking precedence for fields of the same name.
+func RegisterContextExtractor(extractor ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	extractors = append(extractors, extractor)
+}
+
+// NewContext returns a copy of ctx carrying the logger, which can be
+// retrieved with FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, l)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or nil
+// when there is none.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(loggerContextKey).(*Logger)
+	return l
+}
+
+// ContextWithFields returns a copy of ctx carrying the fields, merged
+// with any fields already stored in ctx. They are added to every message
+// logged with the context.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsContextKey, mergeFields(FieldsFromContext(ctx), fields))
+}
+
+// FieldsFromContext returns the fields stored in ctx by ContextWithFields.
+func FieldsFromContext(ctx context.Context) Fields {
+	fields, _ := ctx.Value(fieldsContextKey).(Fields)
+	return fields
+}
+
+// contextFields returns the fields stored in ctx together with those of
+// all registered extractors.
+func contextFields(ctx context.Context) Fields {
+	fields := FieldsFromContext(ctx)
+
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	for _, extractor := range extractors {
+		fields = mergeFields(fields, extractor(ctx))
+	}
+	return fields
+}
+
+// LogWithContext sends a log message to LogDNA with the per-message options
+// and the fields found in ctx. Only the values of ctx are used; its
+// cancellation does not affect delivery of the message.
+func (l *Logger) LogWithContext(ctx context.Context, message string, options Options) error {
+	options.Fields = mergeFields(contextFields(ctx), options.Fields)
+	return l.LogWithOptions(message, options)
+}
+
+// InfoContext logs a message at level Info to LogDNA with the fields found in ctx.
+func (l *Logger) InfoContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "info"})
+}
+
+// WarnContext logs a message at level Warn to LogDNA with the fields found in ctx.
+func (l *Logger) WarnContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "warn"})
+}
+
+// DebugContext logs a message at level Debug to LogDNA with the fields found in ctx.
+func (l *Logger) DebugContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "debug"})
+}
+
+// ErrorContext logs a message at level Error to LogDNA with the fields found in ctx.
+func (l *Logger) ErrorContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "error"})
+}
+
+// FatalContext logs a message at level Fatal to LogDNA with the fields found in ctx,
+// exiting as with Fatal.
+func (l *Logger) FatalContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "fatal"})
+	l.exitFatal()
+}
+
+// CriticalContext logs a message at level Critical to LogDNA with the fields found in ctx.
+func (l *Logger) CriticalContext(ctx context.Context, message string) {
+	l.LogWithContext(ctx, message, Options{Level: "critical"})
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userIDKey struct{}
+
+func TestContext_FromContext(t *testing.T) {
+	l, err := NewLogger(Options{}, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	assert.Nil(t, FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), l)
+	assert.Equal(t, l, FromContext(ctx))
+}
+
+func TestContext_ContextWithFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), Fields{"request": "abc"})
+	ctx = ContextWithFields(ctx, Fields{"tenant": "acme"})
+
+	assert.Equal(t, Fields{"request": "abc", "tenant": "acme"}, FieldsFromContext(ctx))
+	assert.Nil(t, FieldsFromContext(context.Background()))
+}
+
+func TestContext_LogWithContext(t *testing.T) {
+	defer func(saved []ContextExtractor) { extractors = saved }(extractors)
+
+	body := make(map[string](interface{}))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	RegisterContextExtractor(func(ctx context.Context) Fields {
+		if id, ok := ctx.Value(userIDKey{}).(string); ok {
+			return Fields{"user": id}
+		}
+		return nil
+	})
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, IndexMeta: true}, "abc123")
+	assert.Equal(t, nil, err)
+
+	ctx := context.WithValue(context.Background(), userIDKey{}, "alice")
+	ctx = ContextWithFields(ctx, Fields{"request": "abc"})
+
+	// cancellation does not prevent delivery
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	l.WarnContext(ctx, "testing")
+	l.Close()
+
+	if assert.NotEmpty(t, body["lines"]) {
+		line := body["lines"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "warn", line["level"])
+
+		meta := line["meta"].(map[string]interface{})
+		assert.Equal(t, "alice", meta["user"])
+		assert.Equal(t, "abc", meta["request"])
+	}
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repeatCountField is the metadata field holding the number of repeats
+// collapsed into a summary message.
+const repeatCountField = "repeat_count"
+
+const defaultDedupWindow = time.Second
+
+// Dedup configures the collapsing of consecutive identical messages.
+type Dedup struct {
+	// Window is the longest time repeats of a message are collapsed for,
+	// one second by default. A summary is sent when it ends.
+	Window time.Duration
+}
+
+// deduper sends the first of a run of identical consecutive messages and
+// counts the repeats. Messages are identical when their body, level, app,
+// env, metadata, caller and payload-level attributes such as the hostname
+// and tags are equal. When a different message arrives or the window
+// ends, a summary of the repeats is sent.
+type deduper struct {
+	window time.Duration
+	send   func(Message)
+
+	mu      sync.Mutex
+	pending bool
+	last    Message
+	key     string
+	count   int
+	timer   *time.Timer
+}
+
+func newDeduper(options Dedup, send func(Message)) *deduper {
+	if options.Window <= 0 {
+		options.Window = defaultDedupWindow
+	}
+
+	return &deduper{
+		window: options.Window,
+		send:   send,
+	}
+}
+
+func dedupKey(msg Message) string {
+	o := msg.Options
+	return strings.Join([]string{
+		o.Level, o.App, o.Env, o.Hostname, o.IPAddress, o.MacAddress, o.Tags,
+		o.Meta, fieldsKey(o.Fields), msg.file, msg.Body,
+	}, "\x00")
+}
+
+// fieldsKey returns a stable encoding of the fields, as maps are encoded
+// with sorted keys.
+func fieldsKey(f Fields) string {
+	if len(f) == 0 {
+		return ""
+	}
+
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		encoded, _ = json.Marshal(sanitizeMap(f))
+	}
+	return string(encoded)
+}
+
+// add sends the message unless it repeats the pending one. Messages are
+// sent after d.mu is released, so that a transport blocked on
+// Options.BlockOnMemoryLimit does not hold up other loggers.
+func (d *deduper) add(msg Message) {
+	key := dedupKey(msg)
+
+	d.mu.Lock()
+	if d.pending && key == d.key {
+		d.count++
+		d.mu.Unlock()
+		return
+	}
+
+	summary, ok := d.flushLocked()
+	d.pending = true
+	d.last = msg
+	d.key = key
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		// a different message already ended this run
+		if d.timer != timer {
+			d.mu.Unlock()
+			return
+		}
+		summary, ok := d.flushLocked()
+		d.mu.Unlock()
+
+		if ok {
+			d.send(summary)
+		}
+	})
+	d.timer = timer
+	d.mu.Unlock()
+
+	if ok {
+		d.send(summary)
+	}
+	d.send(msg)
+}
+
+// flush sends the summary of any pending repeats.
+func (d *deduper) flush() {
+	d.mu.Lock()
+	summary, ok := d.flushLocked()
+	d.mu.Unlock()
+
+	if ok {
+		d.send(summary)
+	}
+}
+
+// flushLocked ends the pending run, returning the summary of its repeats
+// when there are any.
+func (d *deduper) flushLocked() (Message, bool) {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	var summary Message
+	ok := d.pending && d.count > 0
+	if ok {
+		summary = d.last
+		summary.Body = fmt.Sprintf("last message repeated %d times", d.count)
+		summary.Options.Timestamp = time.Time{}
+		summary.Options.Fields = mergeFields(summary.Options.Fields, Fields{repeatCountField: d.count})
+	}
+
+	d.pending = false
+	d.count = 0
+	return summary, ok
+}
@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dedupRecorder struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func (r *dedupRecorder) send(msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+func (r *dedupRecorder) bodies() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var bodies []string
+	for _, msg := range r.msgs {
+		bodies = append(bodies, msg.Body)
+	}
+	return bodies
+}
+
+func TestDeduper_Add(t *testing.T) {
+	r := &dedupRecorder{}
+	d := newDeduper(Dedup{Window: time.Hour}, r.send)
+
+	for i := 0; i < 5; i++ {
+		d.add(Message{Body: "crash", Options: Options{Level: "error"}})
+	}
+	d.add(Message{Body: "crash", Options: Options{Level: "warn"}})
+	d.add(Message{Body: "recovered"})
+	d.add(Message{Body: "recovered"})
+	d.flush()
+
+	assert.Equal(t, []string{
+		"crash",
+		"last message repeated 4 times",
+		"crash",
+		"recovered",
+		"last message repeated 1 times",
+	}, r.bodies())
+
+	summary := r.msgs[1]
+	assert.Equal(t, "error", summary.Options.Level)
+	assert.Equal(t, 4, summary.Options.Fields[repeatCountField])
+}
+
+func TestDeduper_BlockedSend(t *testing.T) {
+	release := make(chan struct{})
+	sending := make(chan struct{}, 1)
+	r := &dedupRecorder{}
+	d := newDeduper(Dedup{Window: time.Hour}, func(msg Message) {
+		if msg.Body == "slow" {
+			sending <- struct{}{}
+			<-release
+		}
+		r.send(msg)
+	})
+
+	first := make(chan struct{})
+	go func() {
+		d.add(Message{Body: "slow"})
+		close(first)
+	}()
+	<-sending
+
+	// repeats are counted while the first message is still being sent
+	done := make(chan struct{})
+	go func() {
+		d.add(Message{Body: "slow"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("add blocked on a pending send")
+	}
+
+	close(release)
+	<-first
+	d.add(Message{Body: "next"})
+	d.flush()
+	assert.Equal(t, []string{"slow", "last message repeated 1 times", "next"}, r.bodies())
+}
+
+func TestDeduper_Key(t *testing.T) {
+	r := &dedupRecorder{}
+	d := newDeduper(Dedup{Window: time.Hour}, r.send)
+
+	d.add(Message{Body: "login", Options: Options{Fields: Fields{"user": "a"}}})
+	d.add(Message{Body: "login", Options: Options{Fields: Fields{"user": "b"}}})
+	d.add(Message{Body: "login", Options: Options{Fields: Fields{"user": "b"}, Hostname: "web-1"}})
+	d.add(Message{Body: "login", Options: Options{Fields: Fields{"user": "b"}, Hostname: "web-1", Tags: "web"}})
+	d.add(Message{Body: "login", Options: Options{Fields: Fields{"user": "b"}, Hostname: "web-1", Tags: "web"}})
+	d.flush()
+
+	if assert.Equal(t, 5, len(r.msgs)) {
+		assert.Equal(t, "a", r.msgs[0].Options.Fields["user"])
+		assert.Equal(t, "b", r.msgs[1].Options.Fields["user"])
+		assert.Equal(t, "web-1", r.msgs[2].Options.Hostname)
+		assert.Equal(t, "web", r.msgs[3].Options.Tags)
+		assert.Equal(t, "last message repeated 1 times", r.msgs[4].Body)
+	}
+}
+
+func TestDeduper_Window(t *testing.T) {
+	r := &dedupRecorder{}
+	d := newDeduper(Dedup{Window: 20 * time.Millisecond}, r.send)
+
+	d.add(Message{Body: "crash"})
+	d.add(Message{Body: "crash"})
+	d.add(Message{Body: "crash"})
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"crash", "last message repeated 2 times"}, r.bodies())
+
+	// a new run starts once the window has ended
+	d.add(Message{Body: "crash"})
+	assert.Equal(t, []string{"crash", "last message repeated 2 times", "crash"}, r.bodies())
+	d.flush()
+}
+
+func TestLogger_Dedup(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		IndexMeta: true,
+		Dedup:     &Dedup{Window: time.Hour},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 100; i++ {
+		l.Error("crash")
+	}
+	// pending summary is flushed by Close
+	l.Close()
+
+	if assert.Equal(t, 2, len(lines)) {
+		line := lines[1].(map[string]interface{})
+		assert.Equal(t, "last message repeated 99 times", line["line"])
+		assert.Equal(t, "error", line["level"])
+		assert.Equal(t, float64(99), line["meta"].(map[string]interface{})[repeatCountField])
+	}
+}
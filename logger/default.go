@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxEarlyLines is the number of lines logged through the no-op default
+// logger which are kept until a default logger is set.
+const maxEarlyLines = 1000
+
+var std = newDefaultLogger()
+
+// errDerivedDefault is returned by SetDefault for loggers derived from the
+// no-op default, which would send their lines back to themselves.
+var errDerivedDefault = errors.New("logger: SetDefault requires a logger created with NewLogger, not derived from Default")
+
+// defaultLogger holds the package-level logger. Until one is set with
+// SetDefault, a no-op logger buffers early lines so that they can be
+// replayed once a real logger is installed.
+type defaultLogger struct {
+	current atomic.Value // *Logger
+	noop    *Logger
+
+	// setMu serializes SetDefault calls, while mu guards the state shared
+	// with capture. Lines are never logged while holding mu.
+	setMu     sync.Mutex
+	mu        sync.Mutex
+	installed *Logger
+	replaying bool
+	early     []earlyLine
+	dropped   int
+}
+
+// earlyLine is a message logged before a default logger was set, along
+// with the logger derived from the no-op default it was logged with.
+type earlyLine struct {
+	from *Logger
+	msg  Message
+}
+
+func newDefaultLogger() *defaultLogger {
+	d := &defaultLogger{}
+
+	// the transport of the no-op logger is never used, as every message
+	// is captured before reaching it
+	t := &transport{batches: make(map[batchKey]*batch), done: make(chan struct{})}
+	t.released = sync.NewCond(&t.mu)
+
+	options := Options{}
+	options.setDefaults()
+	d.noop = &Logger{
+		Options:   options,
+		transport: t,
+		level:     NewAtomicLevel(0),
+		levelSpec: &levelSpecHolder{},
+		child:     true,
+		deferred:  d,
+	}
+	d.current.Store(d.noop)
+	return d
+}
+
+// capture buffers a message logged through the no-op logger, or one of
+// its children, or forwards it once a default logger is installed and the
+// early lines are replayed.
+func (d *defaultLogger) capture(from *Logger, msg Message) {
+	d.mu.Lock()
+	installed := d.installed
+	if installed != nil && !d.replaying {
+		d.mu.Unlock()
+		forward(installed, from, msg)
+		return
+	}
+	defer d.mu.Unlock()
+
+	if len(d.early) >= maxEarlyLines {
+		d.dropped++
+		return
+	}
+	if msg.Options.Timestamp.IsZero() {
+		msg.Options.Timestamp = time.Now()
+	}
+	d.early = append(d.early, earlyLine{from: from, msg: msg})
+}
+
+// forward logs a message of a logger derived from the no-op default with
+// the installed default, under the name of the derived logger so that its
+// level is chosen by LevelSpec, and with the hooks added to it.
+func forward(installed *Logger, from *Logger, msg Message) {
+	target := installed
+	if from.name != "" {
+		target = installed.Named(from.name)
+	}
+	if len(from.Options.Hooks) > 0 {
+		target = target.WithHooks(from.Options.Hooks...)
+	}
+	target.LogWithOptions(msg.Body, msg.Options)
+}
+
+func (d *defaultLogger) set(l *Logger) error {
+	if l != nil && l.deferred != nil {
+		return errDerivedDefault
+	}
+
+	d.setMu.Lock()
+	defer d.setMu.Unlock()
+
+	d.mu.Lock()
+	d.installed = l
+	if l == nil {
+		d.current.Store(d.noop)
+		d.mu.Unlock()
+		return nil
+	}
+	d.current.Store(l)
+	d.replaying = true
+	d.mu.Unlock()
+
+	// lines captured while replaying are buffered behind the early lines,
+	// so they are replayed in order until none are left
+	for {
+		d.mu.Lock()
+		early, dropped := d.early, d.dropped
+		d.early, d.dropped = nil, 0
+		if len(early) == 0 && dropped == 0 {
+			d.replaying = false
+			d.mu.Unlock()
+			return nil
+		}
+		d.mu.Unlock()
+
+		for _, line := range early {
+			forward(l, line.from, line.msg)
+		}
+		if dropped > 0 {
+			l.Warnf("%d log lines were dropped before a default logger was set", dropped)
+		}
+	}
+}
+
+// Default returns the package-level logger. Until a logger is set with
+// SetDefault, it is a no-op logger which keeps the first 1000 lines and
+// replays them to the logger passed to SetDefault. Loggers derived from
+// it, for example with Named, send their lines to the current default,
+// under their name and with their own hooks.
+func Default() *Logger {
+	return std.current.Load().(*Logger)
+}
+
+// SetDefault replaces the package-level logger used by Default and the
+// package-level logging functions, replaying any lines buffered before a
+// logger was set. Passing nil restores the no-op logger. Loggers derived
+// from the no-op logger are rejected with an error.
+func SetDefault(l *Logger) error {
+	return std.set(l)
+}
+
+// Log sends a log message through the default logger.
+func Log(message string) {
+	Default().Log(message)
+}
+
+// Info logs a message at level Info through the default logger.
+func Info(message string) {
+	Default().Info(message)
+}
+
+// Warn logs a message at level Warn through the default logger.
+func Warn(message string) {
+	Default().Warn(message)
+}
+
+// Debug logs a message at level Debug through the default logger.
+func Debug(message string) {
+	Default().Debug(message)
+}
+
+// Error logs a message at level Error through the default logger.
+func Error(message string) {
+	Default().Error(message)
+}
+
+// Fatal logs a message at level Fatal through the default logger, exiting
+// when its Options.ExitOnFatal is set.
+func Fatal(message string) {
+	Default().Fatal(message)
+}
+
+// Critical logs a message at level Critical through the default logger.
+func Critical(message string) {
+	Default().Critical(message)
+}
+
+// Infof logs a formatted message at level Info through the default logger.
+func Infof(format string, args ...interface{}) {
+	Default().Infof(format, args...)
+}
+
+// Warnf logs a formatted message at level Warn through the default logger.
+func Warnf(format string, args ...interface{}) {
+	Default().Warnf(format, args...)
+}
+
+// Debugf logs a formatted message at level Debug through the default logger.
+func Debugf(format string, args ...interface{}) {
+	Default().Debugf(format, args...)
+}
+
+// Errorf logs a formatted message at level Error through the default logger.
+func Errorf(format string, args ...interface{}) {
+	Default().Errorf(format, args...)
+}
+
+// Fatalf logs a formatted message at level Fatal through the default
+// logger, exiting as with Fatal.
+func Fatalf(format string, args ...interface{}) {
+	Default().Fatalf(format, args...)
+}
+
+// Criticalf logs a formatted message at level Critical through the default
+// logger.
+func Criticalf(format string, args ...interface{}) {
+	Default().Criticalf(format, args...)
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	defer SetDefault(nil)
+
+	t.Run("No-op default", func(t *testing.T) {
+		assert.NotNil(t, Default())
+
+		// closing the no-op logger has no effect
+		Default().Close()
+		assert.True(t, Default().Flush(0))
+	})
+
+	t.Run("Replays early lines", func(t *testing.T) {
+		db := Default().Named("db")
+		Info("early")
+		db.Errorf("early %d", 2)
+
+		l, msgs := captureLogger(t, Options{App: "app"})
+		defer l.Close()
+		assert.Equal(t, nil, SetDefault(l))
+		assert.Equal(t, l, Default())
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			first := (*msgs)[0]
+			assert.Equal(t, "early", first.Body)
+			assert.Equal(t, "info", first.Options.Level)
+			assert.Equal(t, "app", first.Options.App)
+			assert.False(t, first.Options.Timestamp.IsZero())
+
+			second := (*msgs)[1]
+			assert.Equal(t, "early 2", second.Body)
+			assert.Equal(t, "error", second.Options.Level)
+			assert.Equal(t, "db", second.Options.Fields[nameField])
+		}
+
+		// loggers derived from the no-op logger follow the default
+		db.Warn("late")
+		Debugf("late %d", 2)
+		if assert.Equal(t, 4, len(*msgs)) {
+			assert.Equal(t, "late", (*msgs)[2].Body)
+			assert.Equal(t, "db", (*msgs)[2].Options.Fields[nameField])
+			assert.Equal(t, "late 2", (*msgs)[3].Body)
+		}
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		SetDefault(nil)
+		for i := 0; i < maxEarlyLines+5; i++ {
+			Debug("early")
+		}
+
+		l, msgs := captureLogger(t, Options{})
+		defer l.Close()
+		SetDefault(l)
+
+		if assert.Equal(t, maxEarlyLines+1, len(*msgs)) {
+			last := (*msgs)[maxEarlyLines]
+			assert.Equal(t, "5 log lines were dropped before a default logger was set", last.Body)
+			assert.Equal(t, "warn", last.Options.Level)
+		}
+	})
+
+	t.Run("Derived from the no-op default", func(t *testing.T) {
+		SetDefault(nil)
+		derived := Default().Named("app")
+		derived.Info("early")
+
+		assert.Equal(t, errDerivedDefault, SetDefault(derived))
+		assert.Equal(t, std.noop, Default())
+
+		// the early line is kept for a real logger
+		l, msgs := captureLogger(t, Options{})
+		defer l.Close()
+		assert.Equal(t, nil, SetDefault(l))
+		Info("late")
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			assert.Equal(t, "early", (*msgs)[0].Body)
+			assert.Equal(t, "late", (*msgs)[1].Body)
+		}
+	})
+
+	t.Run("Logging from the replay", func(t *testing.T) {
+		SetDefault(nil)
+		lib := Default().Named("lib")
+		Info("early")
+
+		// a hook of the installed logger logging through a logger derived
+		// from the no-op default must not deadlock, and its line follows
+		// the early lines
+		logged := false
+		o := Options{Hooks: []Hook{func(msg *Message) bool {
+			if !logged {
+				logged = true
+				lib.Info("from hook")
+			}
+			return true
+		}}}
+		l, msgs := captureLogger(t, o)
+		defer l.Close()
+		assert.Equal(t, nil, SetDefault(l))
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			assert.Equal(t, "early", (*msgs)[0].Body)
+			assert.Equal(t, "from hook", (*msgs)[1].Body)
+		}
+	})
+
+	t.Run("Name of derived loggers", func(t *testing.T) {
+		SetDefault(nil)
+		db := Default().Named("db")
+		db.Debug("early")
+
+		l, msgs := captureLogger(t, Options{LevelSpec: "db=debug,*=info"})
+		defer l.Close()
+		assert.Equal(t, nil, SetDefault(l))
+
+		// the level of the name applies
+		db.Debug("late")
+		Debug("dropped")
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			assert.Equal(t, "early", (*msgs)[0].Body)
+			assert.Equal(t, "late", (*msgs)[1].Body)
+		}
+	})
+
+	t.Run("Hooks of derived loggers", func(t *testing.T) {
+		SetDefault(nil)
+		var bodies []string
+		derived := Default().WithHooks(func(msg *Message) bool {
+			bodies = append(bodies, msg.Body)
+			return false
+		})
+		derived.Info("early")
+
+		l, err := NewLogger(Options{}, "abc123")
+		assert.Equal(t, nil, err)
+		defer l.Close()
+		assert.Equal(t, nil, SetDefault(l))
+
+		derived.Info("late")
+		assert.Equal(t, []string{"early", "late"}, bodies)
+	})
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// errorField is the metadata field holding the details of a logged error.
+const errorField = "error"
+
+// fingerprintFrames bounds the stack frames used for error fingerprints.
+const fingerprintFrames = 10
+
+// ErrorFields returns structured metadata describing the error: its
+// message and concrete type, the chain of errors it wraps, a stack trace
+// and a fingerprint. The stack trace is taken from the first error in the
+// chain carrying one, as with github.com/pkg/errors, or else captured at
+// the time of the call. The fingerprint depends only on the types in the
+// chain and the functions in the stack trace, so that occurrences of the
+// same error can be grouped regardless of their messages.
+func ErrorFields(err error) Fields {
+	if err == nil {
+		return nil
+	}
+
+	var chain []interface{}
+	var types []string
+	var frames []runtime.Frame
+	for _, e := range unwrapAll(err) {
+		typ := fmt.Sprintf("%T", e)
+		types = append(types, typ)
+		chain = append(chain, Fields{"message": e.Error(), "type": typ})
+
+		if frames == nil {
+			frames = errorFrames(e)
+		}
+	}
+	if frames == nil {
+		frames = callerFrames()
+	}
+
+	var stack, functions []string
+	for _, frame := range frames {
+		stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, shortPath(frame.File), frame.Line))
+		functions = append(functions, frame.Function)
+	}
+
+	return Fields{errorField: Fields{
+		"message":     err.Error(),
+		"type":        types[0],
+		"chain":       chain,
+		"stack":       stack,
+		"fingerprint": fingerprint(types, functions),
+	}}
+}
+
+// unwrapAll returns the error followed by all the errors it wraps, depth
+// first, including errors joining several others.
+func unwrapAll(err error) []error {
+	var errs []error
+	for err != nil {
+		errs = append(errs, err)
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range multi.Unwrap() {
+				errs = append(errs, unwrapAll(e)...)
+			}
+			break
+		}
+		err = errors.Unwrap(err)
+	}
+	return errs
+}
+
+// errorFrames returns the stack trace carried by the error, from a
+// StackTrace method returning a slice of program counters.
+func errorFrames(err error) []runtime.Frame {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+
+	out := method.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	trace := method.Call(nil)[0]
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return framesOf(pcs, false)
+}
+
+// callerFrames returns the current stack, starting at the code which
+// called into the library.
+func callerFrames() []runtime.Frame {
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	return framesOf(pcs[:n], true)
+}
+
+func framesOf(pcs []uintptr, skipLibrary bool) []runtime.Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	var frames []runtime.Frame
+	iter := runtime.CallersFrames(pcs)
+	for {
+		frame, more := iter.Next()
+		if !skipLibrary || !isLibraryFrame(frame) {
+			skipLibrary = false
+			frames = append(frames, frame)
+		}
+		if !more {
+			return frames
+		}
+	}
+}
+
+func fingerprint(types []string, functions []string) string {
+	if len(functions) > fingerprintFrames {
+		functions = functions[:fingerprintFrames]
+	}
+
+	h := sha1.New()
+	h.Write([]byte(strings.Join(types, "\n")))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(functions, "\n")))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Err logs a message at level Error to LogDNA, with the details of the
+// error described by ErrorFields added to its metadata.
+func (l *Logger) Err(err error, message string) {
+	l.ErrWithOptions(err, message, Options{Level: "error"})
+}
+
+// ErrWithOptions logs a message with the per-message options and the
+// details of the error described by ErrorFields added to its metadata.
+// At level Fatal, it exits as with Fatal.
+func (l *Logger) ErrWithOptions(err error, message string, options Options) error {
+	options.Fields = mergeFields(options.Fields, ErrorFields(err))
+	logErr := l.LogWithOptions(message, options)
+	if logErr != nil {
+		return logErr
+	}
+
+	if level, _ := ParseLevel(l.Options.merge(options).Level); level == FatalLevel {
+		l.exitFatal()
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stackError carries a stack trace the way github.com/pkg/errors does.
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+type stackFrame uintptr
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &stackError{msg: msg, pcs: pcs[:n]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []stackFrame {
+	frames := make([]stackFrame, len(e.pcs))
+	for i, pc := range e.pcs {
+		frames[i] = stackFrame(pc)
+	}
+	return frames
+}
+
+func failingOperation(msg string) error {
+	return newStackError(msg)
+}
+
+func TestError_ErrorFields(t *testing.T) {
+	t.Run("Chain", func(t *testing.T) {
+		_, err := os.Open("/does/not/exist")
+		err = fmt.Errorf("loading config: %w", err)
+
+		fields := ErrorFields(err)[errorField].(Fields)
+		assert.Equal(t, err.Error(), fields["message"])
+		assert.Equal(t, "*fmt.wrapError", fields["type"])
+
+		chain := fields["chain"].([]interface{})
+		if assert.Equal(t, 3, len(chain)) {
+			assert.Equal(t, fmt.Sprintf("%T", &os.PathError{}), chain[1].(Fields)["type"])
+			assert.Equal(t, "syscall.Errno", chain[2].(Fields)["type"])
+		}
+
+		// captured at the time of the call
+		stack := fields["stack"].([]string)
+		assert.True(t, strings.HasPrefix(stack[0], "github.com/logdna/logdna-go/logger.TestError_ErrorFields"))
+	})
+
+	t.Run("Carried stack trace", func(t *testing.T) {
+		err := fmt.Errorf("saving: %w", failingOperation("disk full"))
+
+		fields := ErrorFields(err)[errorField].(Fields)
+		stack := fields["stack"].([]string)
+		assert.Contains(t, stack[0], "newStackError")
+		assert.Contains(t, stack[1], "failingOperation")
+	})
+
+	t.Run("Fingerprint", func(t *testing.T) {
+		// the same error with different messages is grouped together
+		fingerprints := make(map[string]bool)
+		for _, msg := range []string{"disk full", "quota exceeded"} {
+			fields := ErrorFields(failingOperation(msg))[errorField].(Fields)
+			assert.Equal(t, msg, fields["message"])
+			fingerprints[fields["fingerprint"].(string)] = true
+		}
+		assert.Equal(t, 1, len(fingerprints))
+
+		other := ErrorFields(errors.New("disk full"))[errorField].(Fields)
+		assert.False(t, fingerprints[other["fingerprint"].(string)])
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		assert.Nil(t, ErrorFields(nil))
+	})
+}
+
+func TestLogger_Err(t *testing.T) {
+	body := make(map[string](interface{}))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, IndexMeta: true}, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Err(fmt.Errorf("saving: %w", failingOperation("disk full")), "request failed")
+	l.Close()
+
+	if assert.NotEmpty(t, body["lines"]) {
+		line := body["lines"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "request failed", line["line"])
+		assert.Equal(t, "error", line["level"])
+
+		e := line["meta"].(map[string]interface{})[errorField].(map[string]interface{})
+		assert.Equal(t, "saving: disk full", e["message"])
+		assert.Equal(t, 2, len(e["chain"].([]interface{})))
+		assert.NotEmpty(t, e["stack"])
+		assert.NotEmpty(t, e["fingerprint"])
+	}
+}
@@ -0,0 +1,437 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPooledEventBytes is the largest encoder buffer returned to the pool,
+// so that a single large event does not pin memory.
+const maxPooledEventBytes = 64 * 1024
+
+var eventPool = sync.Pool{
+	New: func() interface{} {
+		return &Event{buf: make([]byte, 0, 512)}
+	},
+}
+
+// Event is a log message under construction, which encodes its fields
+// as JSON as they are added. It is obtained from Logger.Event or one of
+// the leveled helpers such as InfoEvent, and sent with Msg, Msgf or Send.
+// An Event must not be used after it is sent.
+//
+// A nil Event, returned when its level is filtered out, ignores every
+// call, so no work is done for messages which would be dropped.
+type Event struct {
+	buf    []byte
+	keys   []string
+	dup    bool
+	logger *Logger
+	level  Level
+}
+
+// Array is a JSON array under construction, added to an Event with
+// Event.Array.
+type Array struct {
+	buf []byte
+}
+
+// Event starts a message at the given level, or returns nil when the
+// level is not enabled for the logger.
+func (l *Logger) Event(level Level) *Event {
+	if !l.Enabled(level) {
+		return nil
+	}
+
+	e := newEvent()
+	e.logger = l
+	e.level = level
+	return e
+}
+
+// TraceEvent starts a message at level Trace.
+func (l *Logger) TraceEvent() *Event {
+	return l.Event(TraceLevel)
+}
+
+// DebugEvent starts a message at level Debug.
+func (l *Logger) DebugEvent() *Event {
+	return l.Event(DebugLevel)
+}
+
+// InfoEvent starts a message at level Info.
+func (l *Logger) InfoEvent() *Event {
+	return l.Event(InfoLevel)
+}
+
+// WarnEvent starts a message at level Warn.
+func (l *Logger) WarnEvent() *Event {
+	return l.Event(WarnLevel)
+}
+
+// ErrorEvent starts a message at level Error.
+func (l *Logger) ErrorEvent() *Event {
+	return l.Event(ErrorLevel)
+}
+
+// CriticalEvent starts a message at level Critical.
+func (l *Logger) CriticalEvent() *Event {
+	return l.Event(CriticalLevel)
+}
+
+// Dict starts a nested object to be added to an Event with Event.Dict.
+func Dict() *Event {
+	return newEvent()
+}
+
+// Arr starts an array to be added to an Event with Event.Array.
+func Arr() *Array {
+	return &Array{buf: make([]byte, 0, 64)}
+}
+
+func newEvent() *Event {
+	e := eventPool.Get().(*Event)
+	e.buf = append(e.buf[:0], '{')
+	e.keys = e.keys[:0]
+	e.dup = false
+	return e
+}
+
+func (e *Event) release() {
+	if cap(e.buf) > maxPooledEventBytes {
+		return
+	}
+	e.logger = nil
+	eventPool.Put(e)
+}
+
+func (e *Event) key(key string) {
+	for _, k := range e.keys {
+		if k == key {
+			e.dup = true
+			break
+		}
+	}
+	e.keys = append(e.keys, key)
+
+	if len(e.buf) > 1 {
+		e.buf = append(e.buf, ',')
+	}
+	e.buf = appendString(e.buf, key)
+	e.buf = append(e.buf, ':')
+}
+
+// Str adds a string field.
+func (e *Event) Str(key, value string) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = appendString(e.buf, value)
+	return e
+}
+
+// Int adds an integer field.
+func (e *Event) Int(key string, value int) *Event {
+	return e.Int64(key, int64(value))
+}
+
+// Int64 adds an integer field.
+func (e *Event) Int64(key string, value int64) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = strconv.AppendInt(e.buf, value, 10)
+	return e
+}
+
+// Uint64 adds an unsigned integer field.
+func (e *Event) Uint64(key string, value uint64) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = strconv.AppendUint(e.buf, value, 10)
+	return e
+}
+
+// Float64 adds a floating-point field. NaN and infinite values, which
+// JSON cannot represent, are added as strings.
+func (e *Event) Float64(key string, value float64) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = appendFloat(e.buf, value)
+	return e
+}
+
+// Bool adds a boolean field.
+func (e *Event) Bool(key string, value bool) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = strconv.AppendBool(e.buf, value)
+	return e
+}
+
+// Dur adds a duration field as a number of milliseconds.
+func (e *Event) Dur(key string, value time.Duration) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = appendFloat(e.buf, float64(value)/float64(time.Millisecond))
+	return e
+}
+
+// Time adds a time field formatted as RFC 3339.
+func (e *Event) Time(key string, value time.Time) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = append(e.buf, '"')
+	e.buf = value.AppendFormat(e.buf, time.RFC3339Nano)
+	e.buf = append(e.buf, '"')
+	return e
+}
+
+// Err adds the details of the error described by ErrorFields under the
+// "error" field. A nil error is ignored.
+func (e *Event) Err(err error) *Event {
+	if e == nil || err == nil {
+		return e
+	}
+	return e.Interface(errorField, ErrorFields(err)[errorField])
+}
+
+// Dict adds a nested object built with the package-level Dict function.
+func (e *Event) Dict(key string, dict *Event) *Event {
+	if e == nil {
+		dict.release()
+		return e
+	}
+	e.key(key)
+	e.buf = append(e.buf, dict.object()...)
+	dict.release()
+	return e
+}
+
+// Array adds an array built with Arr.
+func (e *Event) Array(key string, arr *Array) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = append(append(append(e.buf, '['), arr.buf...), ']')
+	return e
+}
+
+// Interface adds a field of any type, encoded with encoding/json. Values
+// which cannot be encoded are added as their formatted representation.
+func (e *Event) Interface(key string, value interface{}) *Event {
+	if e == nil {
+		return e
+	}
+	e.key(key)
+	e.buf = appendInterface(e.buf, value)
+	return e
+}
+
+// Msg sends the event with the given message body and releases it.
+func (e *Event) Msg(message string) {
+	if e == nil {
+		return
+	}
+
+	// the options of the logger were validated when it was created, and
+	// only the level changes
+	msg := Message{Body: message, Options: e.logger.Options}
+	msg.Options.Level = e.level.String()
+	msg.Options.Meta = eventMeta(msg.Options.Meta, e.object())
+
+	l, level := e.logger, e.level
+	e.release()
+	l.enqueue(msg)
+	if level == FatalLevel {
+		l.exitFatal()
+	}
+}
+
+// Msgf sends the event with a message formatted according to a format
+// specifier.
+func (e *Event) Msgf(format string, args ...interface{}) {
+	if e == nil {
+		return
+	}
+	e.Msg(fmt.Sprintf(format, args...))
+}
+
+// Send sends the event with an empty message body.
+func (e *Event) Send() {
+	e.Msg("")
+}
+
+// object closes the encoded fields of the event, keeping only the last
+// value of a key which was added more than once.
+func (e *Event) object() []byte {
+	e.buf = append(e.buf, '}')
+	if !e.dup {
+		return e.buf
+	}
+
+	// the last of duplicate keys wins when decoded
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(e.buf, &obj); err != nil {
+		return e.buf
+	}
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		return e.buf
+	}
+	return encoded
+}
+
+// eventMeta combines the Meta of the logger with the encoded fields of an
+// event, which take precedence. Meta which is not a JSON object is kept
+// under the "meta" key.
+func eventMeta(meta string, fields []byte) string {
+	if meta == "" {
+		return string(fields)
+	}
+	if len(fields) == 2 {
+		return meta
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(meta), &merged); err != nil || merged == nil {
+		merged = map[string]json.RawMessage{"meta": appendString(nil, meta)}
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &obj); err != nil {
+		return string(fields)
+	}
+	for k, v := range obj {
+		merged[k] = v
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return string(fields)
+	}
+	return string(encoded)
+}
+
+// Str adds a string element.
+func (a *Array) Str(value string) *Array {
+	a.sep()
+	a.buf = appendString(a.buf, value)
+	return a
+}
+
+// Int adds an integer element.
+func (a *Array) Int(value int) *Array {
+	a.sep()
+	a.buf = strconv.AppendInt(a.buf, int64(value), 10)
+	return a
+}
+
+// Float64 adds a floating-point element.
+func (a *Array) Float64(value float64) *Array {
+	a.sep()
+	a.buf = appendFloat(a.buf, value)
+	return a
+}
+
+// Bool adds a boolean element.
+func (a *Array) Bool(value bool) *Array {
+	a.sep()
+	a.buf = strconv.AppendBool(a.buf, value)
+	return a
+}
+
+// Dict adds a nested object built with Dict.
+func (a *Array) Dict(dict *Event) *Array {
+	a.sep()
+	a.buf = append(a.buf, dict.object()...)
+	dict.release()
+	return a
+}
+
+// Interface adds an element of any type, encoded as with Event.Interface.
+func (a *Array) Interface(value interface{}) *Array {
+	a.sep()
+	a.buf = appendInterface(a.buf, value)
+	return a
+}
+
+func (a *Array) sep() {
+	if len(a.buf) > 0 {
+		a.buf = append(a.buf, ',')
+	}
+}
+
+func appendFloat(buf []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendString(buf, strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return strconv.AppendFloat(buf, f, 'f', -1, 64)
+}
+
+func appendInterface(buf []byte, value interface{}) []byte {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, err = json.Marshal(sanitize(value))
+		if err != nil {
+			return appendString(buf, fmt.Sprintf("%+v", value))
+		}
+	}
+	return append(buf, encoded...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a JSON string. Invalid UTF-8 is replaced by
+// the Unicode replacement character.
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, `�`...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
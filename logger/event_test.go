@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeMeta(t *testing.T, msg Message) map[string]interface{} {
+	var meta map[string]interface{}
+	assert.Equal(t, nil, json.Unmarshal([]byte(msg.Options.Meta), &meta))
+	return meta
+}
+
+func TestEvent_Fields(t *testing.T) {
+	l, msgs := captureLogger(t, Options{})
+	defer l.Close()
+
+	ts := time.Date(2020, 6, 2, 10, 0, 0, 0, time.UTC)
+	l.InfoEvent().
+		Str("user", "a\"b\n").
+		Int("status", 200).
+		Uint64("bytes", 1024).
+		Float64("ratio", 0.5).
+		Float64("nan", math.NaN()).
+		Bool("cached", true).
+		Dur("latency", 1500*time.Microsecond).
+		Time("at", ts).
+		Dict("http", Dict().Str("method", "GET").Int("status", 200)).
+		Array("tags", Arr().Str("a").Int(1).Dict(Dict().Bool("ok", true))).
+		Interface("ids", []int{1, 2}).
+		Err(errors.New("failed")).
+		Msgf("request %d done", 1)
+
+	if assert.Equal(t, 1, len(*msgs)) {
+		msg := (*msgs)[0]
+		assert.Equal(t, "request 1 done", msg.Body)
+		assert.Equal(t, "info", msg.Options.Level)
+
+		meta := decodeMeta(t, msg)
+		assert.Equal(t, "a\"b\n", meta["user"])
+		assert.Equal(t, float64(200), meta["status"])
+		assert.Equal(t, float64(1024), meta["bytes"])
+		assert.Equal(t, 0.5, meta["ratio"])
+		assert.Equal(t, "NaN", meta["nan"])
+		assert.Equal(t, true, meta["cached"])
+		assert.Equal(t, 1.5, meta["latency"])
+		assert.Equal(t, "2020-06-02T10:00:00Z", meta["at"])
+		assert.Equal(t, map[string]interface{}{"method": "GET", "status": float64(200)}, meta["http"])
+		assert.Equal(t, []interface{}{"a", float64(1), map[string]interface{}{"ok": true}}, meta["tags"])
+		assert.Equal(t, []interface{}{float64(1), float64(2)}, meta["ids"])
+		assert.Equal(t, "failed", meta[errorField].(map[string]interface{})["message"])
+	}
+}
+
+func TestEvent_Context(t *testing.T) {
+	t.Run("Child logger", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{})
+		defer l.Close()
+
+		l.With(Fields{"request": "abc"}).WarnEvent().Str("user", "u1").Send()
+
+		if assert.Equal(t, 1, len(*msgs)) {
+			msg := (*msgs)[0]
+			assert.Equal(t, "warn", msg.Options.Level)
+			assert.Equal(t, "abc", msg.Options.Fields["request"])
+			assert.Equal(t, "u1", decodeMeta(t, msg)["user"])
+		}
+	})
+
+	t.Run("Logger meta", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{Meta: `{"user": "default", "region": "us"}`})
+		defer l.Close()
+
+		l.InfoEvent().Str("user", "u1").Msg("testing")
+		l.InfoEvent().Msg("testing")
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			meta := decodeMeta(t, (*msgs)[0])
+			assert.Equal(t, "u1", meta["user"])
+			assert.Equal(t, "us", meta["region"])
+			assert.Equal(t, `{"user": "default", "region": "us"}`, (*msgs)[1].Options.Meta)
+		}
+	})
+
+	t.Run("Raw logger meta", func(t *testing.T) {
+		assert.Equal(t, `{"meta":"raw","user":"u1"}`, eventMeta("raw", []byte(`{"user":"u1"}`)))
+	})
+
+	t.Run("Duplicate keys", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{Meta: `{"user": "default", "region": "us"}`})
+		defer l.Close()
+
+		l.InfoEvent().
+			Str("user", "u1").
+			Str("user", "u2").
+			Dict("http", Dict().Int("status", 200).Int("status", 500)).
+			Msg("testing")
+		l.With(Fields{}).InfoEvent().Str("a", "1").Str("a", "2").Send()
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			assert.Equal(t, `{"http":{"status":500},"region":"us","user":"u2"}`, (*msgs)[0].Options.Meta)
+			assert.Equal(t, `{"a":"2","region":"us","user":"default"}`, (*msgs)[1].Options.Meta)
+		}
+	})
+
+	t.Run("Fatal", func(t *testing.T) {
+		code := -1
+		l, msgs := captureLogger(t, Options{ExitOnFatal: true, ExitFunc: func(c int) { code = c }})
+		defer l.Close()
+
+		l.Event(CriticalLevel).Msg("not fatal")
+		assert.Equal(t, -1, code)
+
+		l.Event(FatalLevel).Str("user", "u1").Msg("fatal")
+		assert.Equal(t, 1, code)
+		assert.Equal(t, 2, len(*msgs))
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{MinLevel: WarnLevel})
+		defer l.Close()
+
+		e := l.DebugEvent()
+		assert.Nil(t, e)
+		e.Str("user", "u1").Dict("http", Dict().Int("status", 200)).Msg("testing")
+		assert.Equal(t, 0, len(*msgs))
+	})
+}
+
+func BenchmarkEvent(b *testing.B) {
+	drop := func(msg *Message) bool { return false }
+	l, err := NewLogger(Options{Hooks: []Hook{drop}, MinLevel: InfoLevel}, "abc123")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer l.Close()
+
+	b.Run("Enabled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.InfoEvent().Str("user", "u1").Int("status", 200).Dur("latency", time.Millisecond).Msg("done")
+		}
+	})
+
+	b.Run("Filtered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			l.DebugEvent().Str("user", "u1").Int("status", 200).Dur("latency", time.Millisecond).Msg("done")
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultFatalFlushTimeout = 5 * time.Second
+
+// Flush sends all buffered messages and waits up to timeout for them to be
+// delivered. It reports whether every message was delivered in time. Unlike
+// Close, the logger can still be used afterwards.
+func (l *Logger) Flush(timeout time.Duration) bool {
+	if l.deduper != nil {
+		l.deduper.flush()
+	}
+	return l.transport.flushWait(timeout)
+}
+
+// exitFatal terminates the process after a message at level Fatal when
+// Options.ExitOnFatal is set, flushing buffered messages first for up to
+// Options.FatalFlushTimeout.
+func (l *Logger) exitFatal() {
+	if !l.Options.ExitOnFatal {
+		return
+	}
+
+	l.Flush(l.Options.FatalFlushTimeout)
+	exit := l.Options.ExitFunc
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(1)
+}
+
+// Panic logs a message at level Critical to LogDNA, flushes buffered
+// messages for up to Options.FatalFlushTimeout and panics with the message.
+func (l *Logger) Panic(message string) {
+	l.LogWithLevel(message, "critical")
+	l.Flush(l.Options.FatalFlushTimeout)
+	panic(message)
+}
+
+// Panicf formats a message according to a format specifier and logs it
+// as with Panic.
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	l.LogWithLevel(message, "critical")
+	l.Flush(l.Options.FatalFlushTimeout)
+	panic(message)
+}
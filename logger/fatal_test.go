@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Fatal(t *testing.T) {
+	var mu sync.Mutex
+	var levels []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		for _, line := range payload.Lines {
+			levels = append(levels, line.Level)
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	t.Run("Exit after flush", func(t *testing.T) {
+		levels = nil
+		want := 1
+		code := -1
+		o := Options{
+			IngestURL:     ts.URL,
+			FlushInterval: time.Hour,
+			ExitOnFatal:   true,
+			ExitFunc: func(c int) {
+				// the message is delivered before exiting
+				mu.Lock()
+				assert.Equal(t, want, len(levels))
+				mu.Unlock()
+				code = c
+			},
+		}
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+		defer l.Close()
+
+		l.Fatal("shutting down")
+		assert.Equal(t, 1, code)
+
+		want, code = 2, -1
+		l.Fatalf("shutting down %d", 2)
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("No exit by default", func(t *testing.T) {
+		exited := false
+		o := Options{
+			IngestURL: ts.URL,
+			ExitFunc:  func(int) { exited = true },
+		}
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+		defer l.Close()
+
+		l.Fatal("not shutting down")
+		assert.False(t, exited)
+	})
+}
+
+func TestLogger_Panic(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		for _, line := range payload.Lines {
+			bodies = append(bodies, line.Body)
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, FlushInterval: time.Hour}, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	assert.PanicsWithValue(t, "invalid state 3", func() {
+		l.Panicf("invalid state %d", 3)
+	})
+
+	mu.Lock()
+	assert.Equal(t, []string{"invalid state 3"}, bodies)
+	mu.Unlock()
+}
+
+func TestLogger_Flush(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, FlushInterval: time.Hour}, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Info("slow")
+	assert.False(t, l.Flush(50*time.Millisecond))
+
+	close(release)
+	assert.True(t, l.Flush(time.Second))
+	l.Close()
+}
+
+func TestLogger_FatalErr(t *testing.T) {
+	code := -1
+	l, msgs := captureLogger(t, Options{ExitOnFatal: true, ExitFunc: func(c int) { code = c }})
+	defer l.Close()
+
+	l.Err(errors.New("failed"), "not fatal")
+	assert.Equal(t, -1, code)
+
+	l.ErrWithOptions(errors.New("failed"), "fatal", Options{Level: "fatal"})
+	assert.Equal(t, 1, code)
+	assert.Equal(t, 2, len(*msgs))
+}
+
+func TestMultiLogger_Fatal(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	code := -1
+	ml, err := NewMultiLogger([]Destination{
+		{Key: "abc123", Options: Options{IngestURL: ts.URL, FlushInterval: time.Hour}},
+		{Key: "def456", Options: Options{
+			IngestURL:     ts.URL,
+			FlushInterval: time.Hour,
+			ExitOnFatal:   true,
+			ExitFunc: func(c int) {
+				// every destination is flushed before exiting
+				mu.Lock()
+				assert.Equal(t, 2, calls)
+				mu.Unlock()
+				code = c
+			},
+		}},
+	})
+	assert.Equal(t, nil, err)
+	defer ml.Close()
+
+	ml.Error("not fatal")
+	assert.Equal(t, -1, code)
+
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	ml.Fatal("shutting down")
+	assert.Equal(t, 1, code)
+}
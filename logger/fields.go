@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Fields holds structured metadata for log messages. Fields are
+// serialized by the library and merged with any JSON object in
+// Options.Meta, taking precedence over keys of the same name.
+type Fields map[string]interface{}
+
+// mergeFields returns a new Fields with the entries of overlay added to
+// those of base. Nested maps present in both are merged recursively. The
+// maps of overlay are copied, as callers may reuse them after logging
+// while the message is still buffered.
+func mergeFields(base Fields, overlay Fields) Fields {
+	if len(overlay) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return copyFields(overlay)
+	}
+
+	merged := make(Fields, len(base)+len(overlay))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		baseMap, baseOK := asFields(merged[k])
+		overlayMap, overlayOK := asFields(v)
+		if baseOK && overlayOK {
+			merged[k] = mergeFields(baseMap, overlayMap)
+			continue
+		}
+		merged[k] = copyValue(v)
+	}
+	return merged
+}
+
+// copyFields copies the fields along with their nested maps and slices.
+func copyFields(f Fields) Fields {
+	if f == nil {
+		return nil
+	}
+
+	copied := make(Fields, len(f))
+	for k, v := range f {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case Fields:
+		return copyFields(value)
+	case map[string]interface{}:
+		return map[string]interface{}(copyFields(value))
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, e := range value {
+			copied[i] = copyValue(e)
+		}
+		return copied
+	}
+	return v
+}
+
+func asFields(v interface{}) (Fields, bool) {
+	switch value := v.(type) {
+	case Fields:
+		return value, true
+	case map[string]interface{}:
+		return Fields(value), true
+	}
+	return nil, false
+}
+
+// size approximates the number of bytes the fields occupy once serialized.
+func (f Fields) size() int {
+	n := 0
+	for k, v := range f {
+		n += len(k) + valueSize(v)
+	}
+	return n
+}
+
+// valueSize approximates the encoded size of a value. Values of types
+// which cannot be estimated, such as structs, are measured by encoding
+// them.
+func valueSize(v interface{}) int {
+	switch value := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, time.Duration:
+		return 8
+	case string:
+		return len(value) + 4
+	case Fields:
+		return value.size()
+	case map[string]interface{}:
+		return Fields(value).size()
+	case []interface{}:
+		n := 0
+		for _, e := range value {
+			n += valueSize(e)
+		}
+		return n
+	case []string:
+		n := 0
+		for _, e := range value {
+			n += len(e) + 4
+		}
+		return n
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return len(fmt.Sprintf("%+v", v))
+	}
+	return len(encoded)
+}
+
+// sanitize returns a copy of the value in which everything that cannot
+// be encoded as JSON is replaced by its formatted representation, so that
+// a single bad value does not prevent the line from being sent.
+func sanitize(v interface{}) interface{} {
+	switch value := v.(type) {
+	case Fields:
+		return sanitizeMap(value)
+	case map[string]interface{}:
+		return sanitizeMap(value)
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, e := range value {
+			s[i] = sanitize(e)
+		}
+		return s
+	}
+
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
+
+func sanitizeMap(m map[string]interface{}) map[string]interface{} {
+	s := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		s[k] = sanitize(v)
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields_Size(t *testing.T) {
+	type blob struct{ Data string }
+	assert.True(t, Fields{"v": blob{Data: strings.Repeat("a", 1000)}}.size() > 1000)
+	assert.True(t, Fields{"v": []string{strings.Repeat("a", 1000)}}.size() > 1000)
+	assert.Equal(t, 9, Fields{"v": 200}.size())
+}
+
+func TestFields_MergeFields(t *testing.T) {
+	base := Fields{"foo": "bar", "baz": "base"}
+	merged := mergeFields(base, Fields{"baz": "merge", "qux": 1})
+
+	assert.Equal(t, Fields{"foo": "bar", "baz": "merge", "qux": 1}, merged)
+	assert.Equal(t, "base", base["baz"])
+
+	t.Run("Nested", func(t *testing.T) {
+		base := Fields{"http": Fields{"method": "GET", "path": "/"}}
+		merged := mergeFields(base, Fields{"http": map[string]interface{}{"status": 200}})
+
+		assert.Equal(t, Fields{"http": Fields{"method": "GET", "path": "/", "status": 200}}, merged)
+		assert.Equal(t, Fields{"method": "GET", "path": "/"}, base["http"])
+	})
+}
+
+func TestFields_MetaEnvelope(t *testing.T) {
+	testCases := []struct {
+		label    string
+		envelope metaEnvelope
+		expected string
+	}{
+		{"Raw meta", metaEnvelope{meta: `{"foo":"bar"}`}, `"{\"foo\":\"bar\"}"`},
+		{"Raw meta indexed", metaEnvelope{indexed: true, meta: `{"foo":"bar"}`}, `{"foo":"bar"}`},
+		{"Invalid raw meta", metaEnvelope{meta: `{"foo":`}, `"{\"foo\":"`},
+		{"Invalid raw meta indexed", metaEnvelope{indexed: true, meta: `{"foo":`}, `"{\"foo\":"`},
+		{"Fields", metaEnvelope{fields: Fields{"foo": "bar"}}, `"{\"foo\":\"bar\"}"`},
+		{"Fields indexed", metaEnvelope{indexed: true, fields: Fields{"foo": "bar"}}, `{"foo":"bar"}`},
+		{"Fields merged into raw meta", metaEnvelope{indexed: true, meta: `{"foo":"bar","baz":1}`, fields: Fields{"baz": 2}}, `{"baz":2,"foo":"bar"}`},
+		{"Fields with invalid raw meta", metaEnvelope{indexed: true, meta: `null`, fields: Fields{"foo": "bar"}}, `{"foo":"bar","meta":"null"}`},
+		{"Unencodable field", metaEnvelope{indexed: true, fields: Fields{"ch": make(chan int), "nested": Fields{"fn": func() {}}}}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			b, err := json.Marshal(tc.envelope)
+			assert.Equal(t, nil, err)
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, string(b))
+			} else {
+				assert.True(t, json.Valid(b))
+			}
+		})
+	}
+}
+
+func TestLogger_LogWithFieldsReuse(t *testing.T) {
+	var mu sync.Mutex
+	var values []float64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Lines []struct {
+				Meta map[string]interface{} `json:"meta"`
+			} `json:"lines"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		for _, line := range payload.Lines {
+			values = append(values, line.Meta["i"].(float64))
+		}
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, IndexMeta: true, MaxBufferLen: 5}, "abc123")
+	assert.Equal(t, nil, err)
+
+	// the map is reused while earlier messages are being sent
+	f := Fields{"nested": map[string]interface{}{}}
+	for i := 0; i < 50; i++ {
+		f["i"] = i
+		f["nested"].(map[string]interface{})["i"] = i
+		l.LogWithFields("testing", f)
+	}
+	l.Close()
+
+	sort.Float64s(values)
+	if assert.Equal(t, 50, len(values)) {
+		for i, v := range values {
+			assert.Equal(t, float64(i), v)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package logger
+
+import "fmt"
+
+// badKey is the field under which values without a valid key are recorded
+// by the key-value logging methods.
+const badKey = "!BADKEY"
+
+// fieldsFromKeysAndValues converts alternating keys and values into Fields.
+// A key which is not a string, or a final key without a value, is treated
+// as a value and recorded under badKey so that it is not silently lost.
+func fieldsFromKeysAndValues(keysAndValues []interface{}) Fields {
+	fields := make(Fields, len(keysAndValues)/2)
+	var bad []interface{}
+	for len(keysAndValues) > 0 {
+		key, ok := keysAndValues[0].(string)
+		if !ok || len(keysAndValues) == 1 {
+			bad = append(bad, keysAndValues[0])
+			keysAndValues = keysAndValues[1:]
+			continue
+		}
+
+		fields[key] = keysAndValues[1]
+		keysAndValues = keysAndValues[2:]
+	}
+
+	if len(bad) > 0 {
+		fields[badKey] = bad
+	}
+	return fields
+}
+
+// Logf formats a message according to a format specifier and sends it to
+// LogDNA with a parameterized level.
+func (l *Logger) Logf(level string, format string, args ...interface{}) error {
+	if l.skip(Options{Level: level}) {
+		return nil
+	}
+	return l.LogWithLevel(fmt.Sprintf(format, args...), level)
+}
+
+// Logw sends a log message to LogDNA with a parameterized level, adding
+// the alternating keys and values as structured metadata.
+func (l *Logger) Logw(level string, message string, keysAndValues ...interface{}) error {
+	if l.skip(Options{Level: level}) {
+		return nil
+	}
+
+	options := Options{
+		Level:  level,
+		Fields: fieldsFromKeysAndValues(keysAndValues),
+	}
+	return l.LogWithOptions(message, options)
+}
+
+// Infof logs a formatted message at level Info to LogDNA.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Logf("info", format, args...)
+}
+
+// Warnf logs a formatted message at level Warn to LogDNA.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.Logf("warn", format, args...)
+}
+
+// Debugf logs a formatted message at level Debug to LogDNA.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Logf("debug", format, args...)
+}
+
+// Errorf logs a formatted message at level Error to LogDNA.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Logf("error", format, args...)
+}
+
+// Fatalf logs a formatted message at level Fatal to LogDNA, exiting as
+// with Fatal.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.Logf("fatal", format, args...)
+	l.exitFatal()
+}
+
+// Criticalf logs a formatted message at level Critical to LogDNA.
+func (l *Logger) Criticalf(format string, args ...interface{}) {
+	l.Logf("critical", format, args...)
+}
+
+// Infow logs a message at level Info to LogDNA with key-value metadata.
+func (l *Logger) Infow(message string, keysAndValues ...interface{}) {
+	l.Logw("info", message, keysAndValues...)
+}
+
+// Warnw logs a message at level Warn to LogDNA with key-value metadata.
+func (l *Logger) Warnw(message string, keysAndValues ...interface{}) {
+	l.Logw("warn", message, keysAndValues...)
+}
+
+// Debugw logs a message at level Debug to LogDNA with key-value metadata.
+func (l *Logger) Debugw(message string, keysAndValues ...interface{}) {
+	l.Logw("debug", message, keysAndValues...)
+}
+
+// Errorw logs a message at level Error to LogDNA with key-value metadata.
+func (l *Logger) Errorw(message string, keysAndValues ...interface{}) {
+	l.Logw("error", message, keysAndValues...)
+}
+
+// Fatalw logs a message at level Fatal to LogDNA with key-value metadata,
+// exiting as with Fatal.
+func (l *Logger) Fatalw(message string, keysAndValues ...interface{}) {
+	l.Logw("fatal", message, keysAndValues...)
+	l.exitFatal()
+}
+
+// Criticalw logs a message at level Critical to LogDNA with key-value metadata.
+func (l *Logger) Criticalw(message string, keysAndValues ...interface{}) {
+	l.Logw("critical", message, keysAndValues...)
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat_FieldsFromKeysAndValues(t *testing.T) {
+	testCases := []struct {
+		label         string
+		keysAndValues []interface{}
+		fields        Fields
+	}{
+		{"Empty", nil, Fields{}},
+		{"Pairs", []interface{}{"user", "alice", "status", 200}, Fields{"user": "alice", "status": 200}},
+		{"Odd count", []interface{}{"user", "alice", "status"}, Fields{"user": "alice", badKey: []interface{}{"status"}}},
+		{"Non-string key", []interface{}{42, "user", "alice"}, Fields{"user": "alice", badKey: []interface{}{42}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			assert.Equal(t, tc.fields, fieldsFromKeysAndValues(tc.keysAndValues))
+		})
+	}
+}
+
+func TestFormat_LeveledMethods(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, IndexMeta: true}, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Infof("request %d of %s", 1, "alice")
+	l.Errorw("request failed", "user", "alice", "status", 500)
+	l.Close()
+
+	if assert.Equal(t, 2, len(lines)) {
+		line := lines[0].(map[string]interface{})
+		assert.Equal(t, "request 1 of alice", line["line"])
+		assert.Equal(t, "info", line["level"])
+
+		line = lines[1].(map[string]interface{})
+		assert.Equal(t, "request failed", line["line"])
+		assert.Equal(t, "error", line["level"])
+
+		meta := line["meta"].(map[string]interface{})
+		assert.Equal(t, "alice", meta["user"])
+		assert.Equal(t, float64(500), meta["status"])
+	}
+}
@@ -0,0 +1,48 @@
+package logger
+
+// Hook inspects a message before it is buffered. Hooks may modify any part
+// of the message, such as its body, level, app, env, metadata or timestamp,
+// and return false to drop it. The message's Timestamp is zero unless it
+// was set explicitly; a zero Timestamp is replaced by the time the message
+// is sent.
+//
+// The Fields of a message may be shared with the logger, so hooks must not
+// modify them in place; use Message.AddFields instead. When
+// Options.Redaction is set, hooks receive the redacted message, and any
+// data they add is not redacted.
+type Hook func(msg *Message) bool
+
+// AddFields merges the fields into the message's metadata without
+// modifying the Fields it shares with its logger.
+func (m *Message) AddFields(fields Fields) {
+	m.Options.Fields = mergeFields(m.Options.Fields, fields)
+}
+
+// WithHooks returns a child logger which shares the transport of l and
+// runs the hooks after those of l.
+func (l *Logger) WithHooks(hooks ...Hook) *Logger {
+	child := l.newChild()
+	child.Options.Hooks = append(append([]Hook(nil), l.Options.Hooks...), hooks...)
+	return child
+}
+
+// runHooks runs the hooks in order, stopping at the first which drops the
+// message. It reports whether the message is kept.
+func runHooks(hooks []Hook, msg *Message) bool {
+	for _, hook := range hooks {
+		if !hook(msg) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForwardHook returns a Hook which sends a copy of every message to the
+// destination logger, for example to send some messages to a second
+// account. The destination's own minimum level and hooks are applied.
+func ForwardHook(destination *Logger) Hook {
+	return func(msg *Message) bool {
+		destination.enqueue(*msg)
+		return true
+	}
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHook_RunHooks(t *testing.T) {
+	var calls []string
+	hooks := []Hook{
+		func(msg *Message) bool {
+			calls = append(calls, "first")
+			msg.Body = strings.ToUpper(msg.Body)
+			return true
+		},
+		func(msg *Message) bool {
+			calls = append(calls, "second")
+			return msg.Body != "DROP"
+		},
+		func(msg *Message) bool {
+			calls = append(calls, "third")
+			return true
+		},
+	}
+
+	msg := Message{Body: "keep"}
+	assert.True(t, runHooks(hooks, &msg))
+	assert.Equal(t, "KEEP", msg.Body)
+	assert.Equal(t, []string{"first", "second", "third"}, calls)
+
+	calls = nil
+	msg = Message{Body: "drop"}
+	assert.False(t, runHooks(hooks, &msg))
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestLogger_Hooks(t *testing.T) {
+	var mu sync.Mutex
+	lines := make(map[string][]interface{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		key := r.Header.Get("apikey")
+		lines[key] = append(lines[key], body["lines"].([]interface{})...)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	audit, err := NewLogger(Options{IngestURL: ts.URL}, "audit")
+	assert.Equal(t, nil, err)
+
+	shared := Fields{"service": "api"}
+	o := Options{
+		IngestURL: ts.URL,
+		IndexMeta: true,
+		Fields:    shared,
+		Hooks: []Hook{
+			func(msg *Message) bool {
+				msg.AddFields(Fields{"enriched": true})
+				return true
+			},
+		},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	child := l.WithHooks(
+		func(msg *Message) bool {
+			return !strings.HasPrefix(msg.Body, "health")
+		},
+		func(msg *Message) bool {
+			if msg.Options.Level == "error" {
+				return ForwardHook(audit)(msg)
+			}
+			return true
+		},
+	)
+
+	child.Info("healthcheck")
+	child.Info("request")
+	child.Error("failure")
+	l.Info("healthcheck")
+	l.Close()
+	audit.Close()
+
+	assert.Equal(t, Fields{"service": "api"}, shared)
+
+	if assert.Equal(t, 3, len(lines["abc123"])) {
+		for _, line := range lines["abc123"] {
+			meta := line.(map[string]interface{})["meta"].(map[string]interface{})
+			assert.Equal(t, true, meta["enriched"])
+		}
+	}
+	if assert.Equal(t, 1, len(lines["audit"])) {
+		assert.Equal(t, "failure", lines["audit"][0].(map[string]interface{})["line"])
+	}
+}
@@ -0,0 +1,96 @@
+package logger
+
+import "encoding/json"
+
+// Lazy is a field value computed only when the message is going to be
+// sent, for metadata which is expensive to produce. The function is
+// called on the logging goroutine after the message passes the minimum
+// level, so the value reflects the state at the time of the call. It is
+// called before redaction, hooks and sampling, which see the computed
+// value, so it is also called for messages dropped by Options.Sampling.
+type Lazy func() interface{}
+
+// MarshalJSON encodes the computed value, for lazy values encoded outside
+// of a message's fields.
+func (fn Lazy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fn())
+}
+
+// resolveLazy returns the fields with every Lazy value replaced by its
+// result, copying only the maps which contain one so that fields shared
+// with the logger are left untouched.
+func resolveLazy(f Fields) Fields {
+	resolved, changed := resolveLazyMap(f)
+	if !changed {
+		return f
+	}
+	return Fields(resolved)
+}
+
+func resolveLazyMap(m map[string]interface{}) (map[string]interface{}, bool) {
+	var resolved map[string]interface{}
+	for k, v := range m {
+		value, changed := resolveLazyValue(v)
+		if !changed {
+			continue
+		}
+		if resolved == nil {
+			resolved = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				resolved[k] = v
+			}
+		}
+		resolved[k] = value
+	}
+	if resolved == nil {
+		return m, false
+	}
+	return resolved, true
+}
+
+func resolveLazyValue(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case Lazy:
+		return value(), true
+	case Fields:
+		resolved, changed := resolveLazyMap(value)
+		return Fields(resolved), changed
+	case map[string]interface{}:
+		return resolveLazyMap(value)
+	case []interface{}:
+		var resolved []interface{}
+		for i, e := range value {
+			r, changed := resolveLazyValue(e)
+			if !changed {
+				continue
+			}
+			if resolved == nil {
+				resolved = append([]interface{}(nil), value...)
+			}
+			resolved[i] = r
+		}
+		if resolved == nil {
+			return v, false
+		}
+		return resolved, true
+	}
+	return v, false
+}
+
+// LogFunc sends a log message to LogDNA with the per-message options,
+// calling message for its body only when the message passes the minimum
+// level.
+func (l *Logger) LogFunc(message func() string, options Options) error {
+	if l.skip(options) {
+		return nil
+	}
+
+	msg, err := l.message("", options)
+	if err != nil {
+		return err
+	}
+
+	msg.Body = message()
+	l.enqueue(msg)
+	return nil
+}
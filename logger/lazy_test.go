@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Lazy(t *testing.T) {
+	t.Run("Evaluated when enabled", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{MinLevel: InfoLevel})
+		defer l.Close()
+
+		calls := 0
+		state := "before"
+		expensive := Lazy(func() interface{} {
+			calls++
+			return state
+		})
+
+		l.Debugw("testing", "state", expensive)
+		assert.Equal(t, 0, calls)
+
+		l.LogWithFields("testing", Fields{
+			"state":  expensive,
+			"nested": Fields{"list": []interface{}{expensive, 1}},
+		})
+		state = "after"
+
+		assert.Equal(t, 2, calls)
+		if assert.Equal(t, 1, len(*msgs)) {
+			fields := (*msgs)[0].Options.Fields
+			assert.Equal(t, "before", fields["state"])
+			assert.Equal(t, Fields{"list": []interface{}{"before", 1}}, fields["nested"])
+		}
+	})
+
+	t.Run("Logger fields", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{})
+		defer l.Close()
+
+		n := 0
+		child := l.With(Fields{"n": Lazy(func() interface{} {
+			n++
+			return n
+		})})
+		child.Info("first")
+		child.Info("second")
+
+		if assert.Equal(t, 2, len(*msgs)) {
+			assert.Equal(t, 1, (*msgs)[0].Options.Fields["n"])
+			assert.Equal(t, 2, (*msgs)[1].Options.Fields["n"])
+		}
+	})
+
+	t.Run("Message", func(t *testing.T) {
+		l, msgs := captureLogger(t, Options{MinLevel: InfoLevel})
+		defer l.Close()
+
+		calls := 0
+		message := func() string {
+			calls++
+			return "computed"
+		}
+
+		assert.Equal(t, nil, l.LogFunc(message, Options{Level: "debug"}))
+		assert.Equal(t, 0, calls)
+
+		assert.Equal(t, nil, l.LogFunc(message, Options{Level: "error"}))
+		assert.Equal(t, 1, calls)
+		if assert.Equal(t, 1, len(*msgs)) {
+			assert.Equal(t, "computed", (*msgs)[0].Body)
+		}
+	})
+
+	t.Run("Marshal", func(t *testing.T) {
+		encoded, err := json.Marshal(Fields{"v": Lazy(func() interface{} { return 1 })})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, `{"v":1}`, string(encoded))
+	})
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log message. Levels are ordered from
+// TraceLevel, the least severe, to FatalLevel. The zero value is not a
+// valid level; as Options.MinLevel it disables filtering.
+type Level int8
+
+// Supported levels, in increasing order of severity.
+const (
+	TraceLevel Level = iota + 1
+	DebugLevel
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+	CriticalLevel
+	FatalLevel
+)
+
+var levelNames = map[Level]string{
+	TraceLevel:    "trace",
+	DebugLevel:    "debug",
+	InfoLevel:     "info",
+	WarnLevel:     "warn",
+	ErrorLevel:    "error",
+	CriticalLevel: "critical",
+	FatalLevel:    "fatal",
+}
+
+// levelAliases maps alternative spellings accepted by ParseLevel.
+var levelAliases = map[string]Level{
+	"warning": WarnLevel,
+	"err":     ErrorLevel,
+	"crit":    CriticalLevel,
+}
+
+// String returns the lower-case name of the level, as sent to LogDNA.
+func (level Level) String() string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", level)
+}
+
+// ParseLevel returns the Level named by the string, ignoring case and
+// surrounding whitespace. The aliases "warning", "err" and "crit" are
+// also accepted.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return level, nil
+		}
+	}
+	if level, ok := levelAliases[name]; ok {
+		return level, nil
+	}
+
+	return 0, fmt.Errorf("unknown level: %q", s)
+}
+
+// enabled reports whether a message at the given level passes the minimum
+// level. Messages with a level that cannot be parsed, such as custom
+// levels, are never filtered.
+func enabled(min Level, level string) bool {
+	if min == 0 {
+		return true
+	}
+
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return true
+	}
+	return parsed >= min
+}
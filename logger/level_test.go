@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevel_ParseLevel(t *testing.T) {
+	testCases := []struct {
+		input string
+		level Level
+		err   bool
+	}{
+		{"trace", TraceLevel, false},
+		{"Debug", DebugLevel, false},
+		{"INFO", InfoLevel, false},
+		{"warn", WarnLevel, false},
+		{"Warning", WarnLevel, false},
+		{" error ", ErrorLevel, false},
+		{"err", ErrorLevel, false},
+		{"critical", CriticalLevel, false},
+		{"fatal", FatalLevel, false},
+		{"YourCustomLevel", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			level, err := ParseLevel(tc.input)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, nil, err)
+				assert.Equal(t, tc.level, level)
+			}
+		})
+	}
+}
+
+func TestLevel_Ordering(t *testing.T) {
+	levels := []Level{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, CriticalLevel, FatalLevel}
+	for i := 1; i < len(levels); i++ {
+		assert.True(t, levels[i-1] < levels[i])
+	}
+	assert.Equal(t, "critical", CriticalLevel.String())
+}
+
+func TestLogger_MinLevel(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	l, err := NewLogger(Options{IngestURL: ts.URL, MinLevel: WarnLevel}, "abc123")
+	assert.Equal(t, nil, err)
+
+	assert.False(t, l.Enabled(InfoLevel))
+	assert.True(t, l.Enabled(WarnLevel))
+
+	l.Debug("dropped")
+	l.Info("dropped")
+	l.LogWithLevel("kept", "WARNING")
+	l.Error("kept")
+	l.LogWithLevel("kept", "YourCustomLevel")
+	l.Close()
+
+	assert.Equal(t, 3, len(lines))
+	for _, line := range lines {
+		assert.Equal(t, "kept", line.(map[string]interface{})["line"])
+	}
+}
+
+type levelTestStringer struct {
+	calls *int
+}
+
+func (s levelTestStringer) String() string {
+	*s.calls++
+	return "formatted"
+}
+
+func TestLogger_MinLevelBeforeValidation(t *testing.T) {
+	l, msgs := captureLogger(t, Options{MinLevel: WarnLevel})
+	defer l.Close()
+
+	invalid := Options{Level: "debug", App: strings.Repeat("a", maxOptionLength+1)}
+	assert.Equal(t, nil, l.LogWithOptions("dropped", invalid))
+
+	calls := 0
+	l.Debugf("dropped %s", levelTestStringer{&calls})
+	l.Debugw("dropped", "value", levelTestStringer{&calls})
+	assert.Equal(t, nil, l.LogFunc(func() string {
+		calls++
+		return "dropped"
+	}, invalid))
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 0, len(*msgs))
+
+	invalid.Level = "error"
+	assert.Error(t, l.LogWithOptions("rejected", invalid))
+
+	t.Run("MultiLogger", func(t *testing.T) {
+		filtered := 0
+		ml, err := NewMultiLogger([]Destination{{
+			Key:     "abc123",
+			Options: Options{MinLevel: WarnLevel},
+			Filter: func(Message) bool {
+				filtered++
+				return false
+			},
+		}})
+		assert.Equal(t, nil, err)
+		defer ml.Close()
+
+		invalid.Level = "info"
+		assert.Equal(t, nil, ml.LogWithOptions("dropped", invalid))
+		assert.Equal(t, 0, filtered)
+	})
+}
@@ -2,6 +2,8 @@ package logger
 
 import (
 	"encoding/json"
+	"net/http"
+	"os"
 
 	"github.com/joho/godotenv"
 )
@@ -11,12 +13,25 @@ type Logger struct {
 	Options Options
 
 	transport *transport
+	level     *AtomicLevel
+	levelSpec *levelSpecHolder
+	redactor  *redactor
+	sampler   *sampler
+	deduper   *deduper
+	name      string
+	child     bool
+
+	// deferred is set on the no-op default logger and the loggers derived
+	// from it, whose messages go to the default set with SetDefault.
+	deferred *defaultLogger
 }
 
 // Message represents a single log message and associated options.
 type Message struct {
 	Body    string
 	Options Options
+
+	file string
 }
 
 // Payload contains the properties sent to the ingestion endpoint.
@@ -36,9 +51,18 @@ type Line struct {
 	App       string       `json:"app,omitempty"`
 	Level     string       `json:"level,omitempty"`
 	Env       string       `json:"env,omitempty"`
+	File      string       `json:"file,omitempty"`
 	Meta      metaEnvelope `json:"meta,omitempty"`
 }
 
+// size approximates the number of bytes the message occupies once
+// serialized into a Line.
+func (m Message) size() int {
+	return len(m.Body) + len(m.Options.App) + len(m.Options.Env) +
+		len(m.Options.Level) + len(m.Options.Meta) + m.Options.Fields.size() +
+		len(m.file) + lineOverhead
+}
+
 type ingestAPIResponse struct {
 	status  string
 	batchID string
@@ -49,64 +73,275 @@ type ingestAPIResponse struct {
 type metaEnvelope struct {
 	indexed bool
 	meta    string
+	fields  Fields
 }
 
+// MarshalJSON encodes the metadata as an object when it is indexed, or
+// as a string otherwise. Raw Meta which is not valid JSON is always
+// encoded as a string so that it cannot corrupt the payload.
 func (me metaEnvelope) MarshalJSON() ([]byte, error) {
-	if !me.indexed {
+	if len(me.fields) == 0 {
+		if me.indexed && json.Valid([]byte(me.meta)) {
+			return []byte(me.meta), nil
+		}
 		return json.Marshal(me.meta)
 	}
 
-	return []byte(me.meta), nil
+	obj, err := me.encodeFields()
+	if err != nil {
+		return nil, err
+	}
+
+	if !me.indexed {
+		return json.Marshal(string(obj))
+	}
+
+	return obj, nil
+}
+
+// encodeFields returns the fields as a JSON object, merged into the raw
+// Meta when it holds a JSON object. Otherwise the raw Meta is kept under
+// the "meta" key.
+func (me metaEnvelope) encodeFields() ([]byte, error) {
+	obj := make(map[string]interface{})
+	if me.meta != "" {
+		if err := json.Unmarshal([]byte(me.meta), &obj); err != nil || obj == nil {
+			obj = map[string]interface{}{"meta": me.meta}
+		}
+	}
+	for k, v := range me.fields {
+		obj[k] = v
+	}
+
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		return json.Marshal(sanitizeMap(obj))
+	}
+	return encoded, nil
 }
 
 // NewLogger creates a logger with parametrized options and key.
 // This logger can then be used to send logs into LogDNA.
 func NewLogger(options Options, key string) (*Logger, error) {
 	godotenv.Load(".env")
+	fromEnv := options.LevelSpec == ""
+	if fromEnv {
+		options.LevelSpec = os.Getenv(LevelSpecEnv)
+	}
+
 	err := options.validate()
 	if err != nil {
 		return nil, err
 	}
 
+	// an invalid spec from the environment is reported once the logger
+	// exists rather than preventing it from being created
+	spec, specErr := ParseLevelSpec(options.LevelSpec)
+	if specErr != nil {
+		if !fromEnv {
+			return nil, &optionsError{issues: []fieldIssue{{"LevelSpec", "Invalid format"}}}
+		}
+		options.LevelSpec = ""
+	}
+
 	options.setDefaults()
+	options.Fields = copyFields(options.Fields)
 	logger := Logger{
 		Options:   options,
 		transport: newTransport(options, key),
+		level:     NewAtomicLevel(options.MinLevel),
+		levelSpec: &levelSpecHolder{},
+	}
+	if options.Redaction != nil {
+		logger.redactor = newRedactor(*options.Redaction)
+	}
+	if options.Sampling != nil {
+		logger.sampler = newSampler(*options.Sampling)
+	}
+	if options.Dedup != nil {
+		logger.deduper = newDeduper(*options.Dedup, logger.transport.add)
+	}
+
+	logger.applyLevelSpec(spec)
+	if specErr != nil {
+		logger.Warnf("Ignoring invalid %s: %v", LevelSpecEnv, specErr)
 	}
 
 	return &logger, nil
 }
 
-// Close must be called when finished logging to ensure all buffered logs are sent
+// Close must be called when finished logging to ensure all buffered logs are sent.
+// Closing a child logger has no effect, as its transport belongs to the
+// logger created with NewLogger.
 func (l *Logger) Close() {
+	if l.child {
+		return
+	}
+	if l.deduper != nil {
+		l.deduper.flush()
+	}
 	l.transport.close()
 }
 
+// With returns a child logger which shares the transport of l and adds the
+// fields to every message. Fields are deep-merged with those of l, so
+// nested maps are combined rather than replaced.
+func (l *Logger) With(fields Fields) *Logger {
+	child := l.newChild()
+	child.Options.Fields = mergeFields(l.Options.Fields, fields)
+	return child
+}
+
+// WithApp returns a child logger which shares the transport of l and
+// uses app as the default App.
+func (l *Logger) WithApp(app string) *Logger {
+	child := l.newChild()
+	child.Options.App = app
+	return child
+}
+
+// WithEnv returns a child logger which shares the transport of l and
+// uses env as the default Env.
+func (l *Logger) WithEnv(env string) *Logger {
+	child := l.newChild()
+	child.Options.Env = env
+	return child
+}
+
+func (l *Logger) newChild() *Logger {
+	child := *l
+	child.child = true
+	return &child
+}
+
+// BatchSettings returns the batching limits currently used by the logger.
+// These only change over time when Options.AdaptiveBatching is enabled.
+func (l *Logger) BatchSettings() BatchSettings {
+	return l.transport.settings()
+}
+
+// MemoryUsage returns the approximate memory held for buffered and
+// in-flight lines, along with the number of lines dropped because
+// Options.MaxMemoryBytes was exceeded.
+func (l *Logger) MemoryUsage() MemoryUsage {
+	return l.transport.memoryUsage()
+}
+
 // Log sends a provided log message to LogDNA.
 func (l *Logger) Log(message string) {
-	logMsg := Message{
-		Body:    message,
-		Options: l.Options,
-	}
-	l.transport.add(logMsg)
+	l.LogWithOptions(message, Options{})
 }
 
 // LogWithOptions allows the user to update options uniquely for a given log message
 // before sending the log to LogDNA.
 func (l *Logger) LogWithOptions(message string, options Options) error {
+	if l.skip(options) {
+		return nil
+	}
+
+	logMsg, err := l.message(message, options)
+	if err != nil {
+		return err
+	}
+
+	l.enqueue(logMsg)
+	return nil
+}
+
+// enqueue passes the message through the filtering stages of the logger
+// and adds it to the transport if it is kept.
+func (l *Logger) enqueue(msg Message) {
+	if l.deferred != nil {
+		l.deferred.capture(l, msg)
+		return
+	}
+	if !l.enabled(msg.Options.Level) {
+		return
+	}
+	if l.Options.ReportCaller {
+		l.addCaller(&msg)
+	}
+	msg.Options.Fields = resolveLazy(msg.Options.Fields)
+	// hooks only see redacted messages, so that they cannot copy secrets
+	// elsewhere, for example with ForwardHook
+	if l.redactor != nil {
+		l.redactor.redact(&msg)
+	}
+	if !runHooks(l.Options.Hooks, &msg) {
+		return
+	}
+	if l.sampler != nil && !l.sampler.sample(&msg) {
+		return
+	}
+	if l.deduper != nil {
+		l.deduper.add(msg)
+		return
+	}
+
+	l.transport.add(msg)
+}
+
+// Enabled reports whether messages at the given level are sent by the
+// logger, which allows skipping expensive work for messages which would
+// be dropped.
+func (l *Logger) Enabled(level Level) bool {
+	min := l.minLevel()
+	return min == 0 || level >= min
+}
+
+func (l *Logger) enabled(level string) bool {
+	return enabled(l.minLevel(), level)
+}
+
+// skip reports whether a message with the per-message options is below
+// the minimum level, so that it can be dropped before the options are
+// merged and validated.
+func (l *Logger) skip(options Options) bool {
+	level := options.Level
+	if level == "" {
+		level = l.Options.Level
+	}
+	return !l.enabled(level)
+}
+
+// Level returns the current minimum level of the logger, initially
+// Options.MinLevel, or the level given to its name by Options.LevelSpec.
+func (l *Logger) Level() Level {
+	return l.minLevel()
+}
+
+// SetLevel changes the minimum level of the logger and all loggers sharing
+// its transport. It is safe to call while messages are being logged.
+func (l *Logger) SetLevel(level Level) {
+	l.level.SetLevel(level)
+}
+
+// LevelHandler returns an http.Handler which reports and changes the
+// minimum level of the logger. See AtomicLevel.ServeHTTP.
+func (l *Logger) LevelHandler() http.Handler {
+	return l.level
+}
+
+// message builds a Message from the logger's options merged with the
+// per-message options.
+func (l *Logger) message(message string, options Options) (Message, error) {
 	msgOpts := l.Options.merge(options)
 	err := msgOpts.validate()
 	if err != nil {
-		return err
+		return Message{}, err
 	}
 
-	logMsg := Message{
+	return Message{
 		Body:    message,
 		Options: msgOpts,
-	}
+	}, nil
+}
 
-	l.transport.add(logMsg)
-	return nil
+// LogWithFields sends a log message to LogDNA with structured metadata,
+// which is merged with the logger's Options.Fields.
+func (l *Logger) LogWithFields(message string, fields Fields) error {
+	options := Options{Fields: fields}
+	return l.LogWithOptions(message, options)
 }
 
 // LogWithLevel sends a log message to LogDNA with a parameterized level.
@@ -135,9 +370,11 @@ func (l *Logger) Error(message string) {
 	l.LogWithLevel(message, "error")
 }
 
-// Fatal logs a message at level Fatal to LogDNA.
+// Fatal logs a message at level Fatal to LogDNA. When Options.ExitOnFatal
+// is set, buffered messages are then flushed and the process exits.
 func (l *Logger) Fatal(message string) {
 	l.LogWithLevel(message, "fatal")
+	l.exitFatal()
 }
 
 // Critical logs a message at level Critical to LogDNA.
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -239,6 +240,40 @@ func TestLogger_LogWithMetaIndexed(t *testing.T) {
 	assert.Equal(t, "value2", meta["key2"])
 }
 
+func TestLogger_LogWithFields(t *testing.T) {
+	body := make(map[string](interface{}))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		IndexMeta: true,
+		Meta:      `{"key": "value"}`,
+		Fields:    Fields{"service": "api", "request": "abc"},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.LogWithFields("testing", Fields{"request": "def", "status": 200})
+	l.Close()
+
+	assert.NotEmpty(t, body)
+	assert.NotEmpty(t, body["lines"])
+
+	ls := body["lines"].([]interface{})
+	line := ls[0].(map[string]interface{})
+
+	meta := line["meta"].(map[string](interface{}))
+	assert.Equal(t, "value", meta["key"])
+	assert.Equal(t, "api", meta["service"])
+	assert.Equal(t, "def", meta["request"])
+	assert.Equal(t, float64(200), meta["status"])
+}
+
 func TestLogger_LogLevels(t *testing.T) {
 	body := make(map[string](interface{}))
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -381,3 +416,237 @@ func TestLogger_TransportMaxBufferLen(t *testing.T) {
 	// final flush after Close completes
 	assert.Equal(t, 4, calls)
 }
+
+func TestLogger_TransportMaxBatchBytes(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL:     ts.URL,
+		MaxBatchBytes: 1024,
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	// each line exceeds MaxBatchBytes on its own
+	l.Log(strings.Repeat("a", 1024))
+	l.Log(strings.Repeat("b", 1024))
+	l.Close()
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestLogger_TransportPayloadGroups(t *testing.T) {
+	var mu sync.Mutex
+	payloads := make(map[string]Payload)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		payloads[payload.Hostname] = payload
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		Hostname:  "proxy",
+		Tags:      "proxy",
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Log("testing0")
+	l.LogWithOptions("testing1", Options{Hostname: "web-1", IPAddress: "10.0.0.1", Tags: "web"})
+	l.LogWithOptions("testing2", Options{Hostname: "web-2", MacAddress: "c0:ff:ee:c0:ff:ee"})
+	l.LogWithOptions("testing3", Options{Hostname: "web-1", IPAddress: "10.0.0.1", Tags: "web"})
+	l.Close()
+
+	assert.Equal(t, 3, len(payloads))
+	assert.Equal(t, 1, len(payloads["proxy"].Lines))
+	assert.Equal(t, "proxy", payloads["proxy"].Tags)
+
+	web1 := payloads["web-1"]
+	assert.Equal(t, "10.0.0.1", web1.IPAddress)
+	assert.Equal(t, "web", web1.Tags)
+	assert.Equal(t, 2, len(web1.Lines))
+
+	web2 := payloads["web-2"]
+	assert.Equal(t, "c0:ff:ee:c0:ff:ee", web2.MacAddress)
+	assert.Equal(t, "proxy", web2.Tags)
+}
+
+func TestLogger_AdaptiveBatching(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	fi := 20 * time.Millisecond
+	o := Options{
+		IngestURL:        ts.URL,
+		AdaptiveBatching: true,
+		MaxBufferLen:     2,
+		FlushInterval:    fi,
+		MaxFlushInterval: 4 * fi,
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	s := l.BatchSettings()
+	assert.Equal(t, 2, s.MaxBufferLen)
+	assert.Equal(t, fi, s.FlushInterval)
+	assert.Equal(t, defaultMaxBatchBytes, s.MaxBatchBytes)
+
+	t.Run("Grows under load", func(t *testing.T) {
+		for i := 0; i < 14; i++ {
+			l.Log("testing")
+		}
+
+		s := l.BatchSettings()
+		assert.Equal(t, 16, s.MaxBufferLen)
+		assert.Equal(t, 4*fi, s.FlushInterval)
+	})
+
+	t.Run("Shrinks when idle", func(t *testing.T) {
+		// wait for enough empty intervals to return to the configured values
+		time.Sleep(20 * fi)
+
+		s := l.BatchSettings()
+		assert.Equal(t, 2, s.MaxBufferLen)
+		assert.Equal(t, fi, s.FlushInterval)
+	})
+}
+
+func TestLogger_MaxMemoryBytes(t *testing.T) {
+	t.Run("Drops newest lines", func(t *testing.T) {
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		size := Message{Body: "testing"}.size()
+		o := Options{
+			IngestURL:      ts.URL,
+			MaxBufferLen:   1,
+			MaxMemoryBytes: 2 * size,
+		}
+
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+
+		// both lines are held in-flight by the blocked server
+		l.Log("testing")
+		l.Log("testing")
+		l.Log("testing")
+
+		m := l.MemoryUsage()
+		assert.Equal(t, 2*size, m.Used)
+		assert.Equal(t, 2*size, m.Limit)
+		assert.Equal(t, uint64(1), m.Dropped)
+
+		close(release)
+		l.Close()
+		assert.Equal(t, 0, l.MemoryUsage().Used)
+	})
+
+	t.Run("Blocks callers", func(t *testing.T) {
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+		}))
+		defer ts.Close()
+
+		o := Options{
+			IngestURL:          ts.URL,
+			MaxBufferLen:       1,
+			MaxMemoryBytes:     Message{Body: "testing"}.size(),
+			BlockOnMemoryLimit: true,
+		}
+
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+
+		l.Log("testing")
+
+		logged := make(chan struct{})
+		go func() {
+			l.Log("testing")
+			close(logged)
+		}()
+
+		select {
+		case <-logged:
+			t.Fatal("expected Log to block while the budget is exhausted")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-logged
+		l.Close()
+
+		assert.Equal(t, uint64(0), l.MemoryUsage().Dropped)
+	})
+}
+
+func TestLogger_With(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		App:       "app",
+		Env:       "production",
+		IndexMeta: true,
+		Fields:    Fields{"http": Fields{"method": "GET"}},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	child := l.With(Fields{"request": "abc"}).WithApp("child")
+	grandchild := child.With(Fields{"http": Fields{"status": 200}}).WithEnv("staging")
+
+	assert.Equal(t, l.transport, grandchild.transport)
+
+	// closing a child leaves the shared transport running
+	child.Close()
+	grandchild.Log("testing")
+	l.Log("testing")
+	l.Close()
+
+	if assert.Equal(t, 2, len(lines)) {
+		line := lines[0].(map[string]interface{})
+		assert.Equal(t, "child", line["app"])
+		assert.Equal(t, "staging", line["env"])
+
+		meta := line["meta"].(map[string]interface{})
+		assert.Equal(t, "abc", meta["request"])
+		assert.Equal(t, map[string]interface{}{"method": "GET", "status": float64(200)}, meta["http"])
+
+		line = lines[1].(map[string]interface{})
+		assert.Equal(t, "app", line["app"])
+		assert.Equal(t, "production", line["env"])
+
+		meta = line["meta"].(map[string]interface{})
+		assert.Equal(t, nil, meta["request"])
+	}
+}
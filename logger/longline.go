@@ -0,0 +1,283 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxLineBytes = 32000
+	defaultMaxMetaBytes = 32000
+
+	// truncatedField and partField are the metadata fields describing
+	// lines which exceeded Options.MaxLineBytes or Options.MaxMetaBytes.
+	truncatedField = "truncated"
+	partField      = "part"
+
+	truncationMarker = "...[truncated]"
+	continuedBody    = "...[continued]"
+)
+
+// LongLinePolicy decides what happens to lines larger than
+// Options.MaxLineBytes or metadata larger than Options.MaxMetaBytes.
+type LongLinePolicy int
+
+const (
+	// TruncateLongLines shortens the line, ending it with a marker, and
+	// records the original length under the "truncated" metadata field.
+	// Oversized metadata is shortened by truncating or removing its largest
+	// values first, so that small fields such as the logger name are kept.
+	TruncateLongLines LongLinePolicy = iota
+	// SplitLongLines sends the line as numbered parts sharing an ID under
+	// the "part" metadata field. Oversized metadata is encoded and split
+	// across the parts under the "meta" key of that field.
+	SplitLongLines
+)
+
+// limitLine applies the line and metadata size limits of options to the
+// message, returning the messages to send in its place.
+func limitLine(msg Message, options Options) []Message {
+	metaBytes := metaSize(msg.Options, options.MaxMetaBytes)
+	longBody := len(msg.Body) > options.MaxLineBytes
+	longMeta := metaBytes > options.MaxMetaBytes
+	if !longBody && !longMeta {
+		return []Message{msg}
+	}
+
+	if options.LongLines == SplitLongLines {
+		return splitLine(msg, options, longMeta)
+	}
+
+	truncated := Fields{}
+	if longBody {
+		truncated["line_length"] = len(msg.Body)
+		msg.Body = truncateLine(msg.Body, options.MaxLineBytes)
+	}
+	if longMeta {
+		truncated["meta_length"] = metaBytes
+		truncateMeta(&msg, truncated, options.MaxMetaBytes)
+		return []Message{msg}
+	}
+	msg.AddFields(Fields{truncatedField: truncated})
+	return []Message{msg}
+}
+
+// truncateMeta replaces the Meta and Fields of the message with their
+// combined metadata, in which the largest values are shortened or removed
+// until it fits the limit. The truncated field is always kept.
+func truncateMeta(msg *Message, truncated Fields, limit int) {
+	meta := map[string]interface{}{}
+	encoded, err := metaEnvelope{
+		indexed: true,
+		meta:    msg.Options.Meta,
+		fields:  msg.Options.Fields,
+	}.MarshalJSON()
+	if err == nil {
+		decoded, err := decodeJSON(encoded)
+		if obj, ok := decoded.(map[string]interface{}); err == nil && ok {
+			meta = obj
+		} else if err == nil {
+			meta["meta"] = decoded
+		}
+	}
+	delete(meta, truncatedField)
+
+	// the truncated field is added last, after a comma
+	markerSize := len(truncatedField) + 4 + encodedSize(truncated)
+	for {
+		encoded, err := json.Marshal(meta)
+		excess := len(encoded) + markerSize - limit
+		if err != nil || excess <= 0 {
+			break
+		}
+
+		key, ok := largestKey(meta)
+		if !ok {
+			break
+		}
+		if v, ok := shrinkValue(meta[key], excess); ok {
+			meta[key] = v
+		} else {
+			delete(meta, key)
+		}
+	}
+
+	meta[truncatedField] = truncated
+	msg.Options.Meta = ""
+	msg.Options.Fields = Fields(meta)
+}
+
+// shrinkValue returns v reduced by about excess encoded bytes, shortening
+// its largest part first, or false when it should be removed instead.
+func shrinkValue(v interface{}, excess int) (interface{}, bool) {
+	switch value := v.(type) {
+	case string:
+		n := len(value) - excess - len(truncationMarker)
+		if n <= 0 {
+			return nil, false
+		}
+		return truncateString(value, n) + truncationMarker, true
+	case map[string]interface{}:
+		key, ok := largestKey(value)
+		if !ok {
+			return nil, false
+		}
+		if e, ok := shrinkValue(value[key], excess); ok {
+			value[key] = e
+		} else {
+			delete(value, key)
+		}
+		return value, true
+	case []interface{}:
+		if len(value) == 0 {
+			return nil, false
+		}
+		largest, largestSize := 0, -1
+		for i, e := range value {
+			if size := encodedSize(e); size > largestSize {
+				largest, largestSize = i, size
+			}
+		}
+		if e, ok := shrinkValue(value[largest], excess); ok {
+			value[largest] = e
+		} else {
+			value = append(value[:largest], value[largest+1:]...)
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// largestKey returns the key of the value of m with the largest encoding.
+func largestKey(m map[string]interface{}) (string, bool) {
+	largest, largestSize := "", -1
+	for k, v := range m {
+		if size := len(k) + encodedSize(v); size > largestSize {
+			largest, largestSize = k, size
+		}
+	}
+	return largest, largestSize >= 0
+}
+
+func encodedSize(v interface{}) int {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// metaSize returns the size of the metadata of a message. The estimate
+// does not account for escaping, so the metadata is encoded to measure it
+// exactly once it comes close to the limit.
+func metaSize(options Options, limit int) int {
+	n := len(options.Meta) + options.Fields.size()
+	if n <= limit/2 {
+		return n
+	}
+
+	encoded, err := metaEnvelope{
+		indexed: true,
+		meta:    options.Meta,
+		fields:  options.Fields,
+	}.MarshalJSON()
+	if err != nil {
+		return n
+	}
+	return len(encoded)
+}
+
+func splitLine(msg Message, options Options, longMeta bool) []Message {
+	bodies := splitString(msg.Body, options.MaxLineBytes)
+
+	// the encoded metadata may grow when escaped as a string, so the parts
+	// are kept to half of the limit
+	var metas []string
+	if longMeta {
+		encoded, err := metaEnvelope{
+			indexed: true,
+			meta:    msg.Options.Meta,
+			fields:  msg.Options.Fields,
+		}.MarshalJSON()
+		if err == nil {
+			metas = splitString(string(encoded), options.MaxMetaBytes/2)
+		}
+		msg.Options.Meta = ""
+		msg.Options.Fields = nil
+	}
+
+	count := len(bodies)
+	if len(metas) > count {
+		count = len(metas)
+	}
+	id := partID()
+
+	parts := make([]Message, count)
+	for i := range parts {
+		part := msg
+		part.Body = continuedBody
+		if i < len(bodies) {
+			part.Body = bodies[i]
+		}
+
+		info := Fields{"id": id, "index": i + 1, "count": count}
+		if i < len(metas) {
+			info["meta"] = metas[i]
+		}
+		part.AddFields(Fields{partField: info})
+		parts[i] = part
+	}
+	return parts
+}
+
+// truncateLine shortens the body to the limit, ending it with the marker
+// unless the limit is too small to hold it.
+func truncateLine(body string, limit int) string {
+	if limit <= len(truncationMarker) {
+		return truncateString(body, limit)
+	}
+	return truncateString(body, limit-len(truncationMarker)) + truncationMarker
+}
+
+// truncateString returns the longest prefix of s of at most n bytes which
+// does not end within a UTF-8 sequence.
+func truncateString(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// splitString cuts s into pieces of at most n bytes, without splitting
+// UTF-8 sequences.
+func splitString(s string, n int) []string {
+	if n <= 0 {
+		n = 1
+	}
+
+	var pieces []string
+	for len(s) > n {
+		piece := truncateString(s, n)
+		if piece == "" {
+			_, size := utf8.DecodeRuneInString(s)
+			piece = s[:size]
+		}
+		pieces = append(pieces, piece)
+		s = s[len(piece):]
+	}
+	return append(pieces, s)
+}
+
+func partID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
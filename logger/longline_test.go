@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongLine_Truncate(t *testing.T) {
+	o := Options{MaxLineBytes: 20, MaxMetaBytes: 20}
+
+	t.Run("Short", func(t *testing.T) {
+		msg := Message{Body: "testing", Options: Options{Meta: `{"foo":"bar"}`}}
+		assert.Equal(t, []Message{msg}, limitLine(msg, o))
+	})
+
+	t.Run("Long line", func(t *testing.T) {
+		msg := Message{Body: strings.Repeat("a", 30), Options: Options{Fields: Fields{"foo": "bar"}}}
+		msgs := limitLine(msg, o)
+		if assert.Equal(t, 1, len(msgs)) {
+			assert.Equal(t, "aaaaaa"+truncationMarker, msgs[0].Body)
+			assert.Equal(t, Fields{
+				"foo":          "bar",
+				truncatedField: Fields{"line_length": 30},
+			}, msgs[0].Options.Fields)
+		}
+	})
+
+	t.Run("Small limit", func(t *testing.T) {
+		msg := Message{Body: strings.Repeat("a", 30)}
+		msgs := limitLine(msg, Options{MaxLineBytes: 5, MaxMetaBytes: 20})
+		if assert.Equal(t, 1, len(msgs)) {
+			assert.Equal(t, "aaaaa", msgs[0].Body)
+		}
+	})
+
+	t.Run("Long meta", func(t *testing.T) {
+		msg := Message{Body: "testing", Options: Options{Meta: strings.Repeat("a", 30)}}
+		msgs := limitLine(msg, o)
+		if assert.Equal(t, 1, len(msgs)) {
+			assert.Equal(t, "testing", msgs[0].Body)
+			assert.Equal(t, "", msgs[0].Options.Meta)
+			// measured as encoded, with quotes
+			assert.Equal(t, Fields{truncatedField: Fields{"meta_length": 32}}, msgs[0].Options.Fields)
+		}
+	})
+
+	t.Run("Long meta values", func(t *testing.T) {
+		type blob struct{ Data string }
+		for _, value := range []interface{}{
+			blob{Data: strings.Repeat("a", 30)},
+			[]string{strings.Repeat("a", 30)},
+			[]byte(strings.Repeat("a", 30)),
+		} {
+			msg := Message{Body: "testing", Options: Options{Fields: Fields{"v": value}}}
+			msgs := limitLine(msg, o)
+			if assert.Equal(t, 1, len(msgs)) {
+				assert.Nil(t, msgs[0].Options.Fields["v"])
+				assert.NotNil(t, msgs[0].Options.Fields[truncatedField])
+			}
+		}
+	})
+
+	t.Run("Largest values first", func(t *testing.T) {
+		msg := Message{Body: "testing", Options: Options{
+			Meta: `{"payload":"` + strings.Repeat("p", 500) + `","region":"us"}`,
+			Fields: Fields{
+				"logger":         "db",
+				callerField:      Fields{"file": "main.go", "line": 10},
+				errorField:       Fields{"message": "failed", "stack": strings.Repeat("s", 1000), "fingerprint": "abc123"},
+				sampledOutField:  3,
+				repeatCountField: 2,
+				"items":          []interface{}{"a", strings.Repeat("i", 400)},
+			},
+		}}
+		msgs := limitLine(msg, Options{MaxLineBytes: 100, MaxMetaBytes: 400})
+		if !assert.Equal(t, 1, len(msgs)) {
+			return
+		}
+
+		encoded, err := json.Marshal(msgs[0].Options.Fields)
+		assert.Equal(t, nil, err)
+		assert.True(t, len(encoded) <= 400, "%d bytes", len(encoded))
+		assert.Equal(t, "", msgs[0].Options.Meta)
+
+		var meta map[string]interface{}
+		assert.Equal(t, nil, json.Unmarshal(encoded, &meta))
+		assert.Equal(t, "db", meta["logger"])
+		assert.Equal(t, "us", meta["region"])
+		assert.Equal(t, map[string]interface{}{"file": "main.go", "line": float64(10)}, meta[callerField])
+		assert.Equal(t, "abc123", meta[errorField].(map[string]interface{})["fingerprint"])
+		assert.Equal(t, "failed", meta[errorField].(map[string]interface{})["message"])
+		assert.Equal(t, float64(3), meta[sampledOutField])
+		assert.Equal(t, float64(2), meta[repeatCountField])
+		assert.NotNil(t, meta[truncatedField].(map[string]interface{})["meta_length"])
+		assert.Equal(t, "a", meta["items"].([]interface{})[0])
+	})
+
+	t.Run("UTF-8", func(t *testing.T) {
+		assert.Equal(t, "a", truncateString("aé", 2))
+		assert.Equal(t, []string{"a", "é", "é"}, splitString("aéé", 2))
+	})
+}
+
+func TestLongLine_Split(t *testing.T) {
+	o := Options{MaxLineBytes: 10, MaxMetaBytes: 40, LongLines: SplitLongLines}
+
+	t.Run("Long line", func(t *testing.T) {
+		msg := Message{Body: strings.Repeat("a", 25), Options: Options{App: "app", Fields: Fields{"foo": "bar"}}}
+		msgs := limitLine(msg, o)
+		if assert.Equal(t, 3, len(msgs)) {
+			var body string
+			id := msgs[0].Options.Fields[partField].(Fields)["id"]
+			for i, m := range msgs {
+				body += m.Body
+				assert.Equal(t, "app", m.Options.App)
+				assert.Equal(t, "bar", m.Options.Fields["foo"])
+				assert.Equal(t, Fields{"id": id, "index": i + 1, "count": 3}, m.Options.Fields[partField])
+			}
+			assert.Equal(t, msg.Body, body)
+		}
+	})
+
+	t.Run("Long meta", func(t *testing.T) {
+		fields := Fields{"foo": strings.Repeat("b", 40)}
+		msg := Message{Body: "testing", Options: Options{Fields: fields}}
+		msgs := limitLine(msg, o)
+		if assert.True(t, len(msgs) > 1) {
+			assert.Equal(t, "testing", msgs[0].Body)
+			assert.Equal(t, continuedBody, msgs[1].Body)
+
+			var encoded string
+			for _, m := range msgs {
+				assert.Nil(t, m.Options.Fields["foo"])
+				encoded += m.Options.Fields[partField].(Fields)["meta"].(string)
+			}
+
+			var meta map[string]interface{}
+			assert.Equal(t, nil, json.Unmarshal([]byte(encoded), &meta))
+			assert.Equal(t, fields["foo"], meta["foo"])
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// Destination is a LogDNA account, with its own ingestion key and options,
+// that a MultiLogger sends lines to.
+type Destination struct {
+	Key     string
+	Options Options
+
+	// Filter decides whether a message is sent to this destination.
+	// All messages are sent when it is nil.
+	Filter func(Message) bool
+}
+
+// MultiLogger sends each log message to several destinations. Every
+// destination buffers and sends independently, so a slow or failing
+// destination does not hold up the others.
+type MultiLogger struct {
+	destinations []multiDestination
+}
+
+type multiDestination struct {
+	logger *Logger
+	filter func(Message) bool
+}
+
+// NewMultiLogger creates a logger which sends each message to all of the
+// provided destinations that accept it.
+func NewMultiLogger(destinations []Destination) (*MultiLogger, error) {
+	ml := MultiLogger{}
+	for _, d := range destinations {
+		l, err := NewLogger(d.Options, d.Key)
+		if err != nil {
+			ml.Close()
+			return nil, err
+		}
+
+		ml.destinations = append(ml.destinations, multiDestination{
+			logger: l,
+			filter: d.Filter,
+		})
+	}
+
+	return &ml, nil
+}
+
+// LevelFilter returns a Destination filter accepting only messages
+// logged at one of the given levels. Levels are compared case-insensitively,
+// and aliases accepted by ParseLevel match their level.
+func LevelFilter(levels ...string) func(Message) bool {
+	return func(msg Message) bool {
+		msgLevel, msgErr := ParseLevel(msg.Options.Level)
+		for _, level := range levels {
+			if strings.EqualFold(level, msg.Options.Level) {
+				return true
+			}
+			if parsed, err := ParseLevel(level); err == nil && msgErr == nil && parsed == msgLevel {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Close flushes and closes every destination.
+func (ml *MultiLogger) Close() {
+	var wg sync.WaitGroup
+	for _, d := range ml.destinations {
+		wg.Add(1)
+		go func(l *Logger) {
+			l.Close()
+			wg.Done()
+		}(d.logger)
+	}
+	wg.Wait()
+}
+
+// Log sends a provided log message to every destination.
+func (ml *MultiLogger) Log(message string) {
+	ml.LogWithOptions(message, Options{})
+}
+
+// LogWithOptions merges the options with those of each destination and
+// sends the log message to every destination accepting it. Destinations
+// for which the merged options are invalid are skipped, and the first
+// validation error is returned.
+func (ml *MultiLogger) LogWithOptions(message string, options Options) error {
+	var firstErr error
+	for _, d := range ml.destinations {
+		if d.logger.skip(options) {
+			continue
+		}
+
+		msg, err := d.logger.message(message, options)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if d.filter != nil && !d.filter(msg) {
+			continue
+		}
+		d.logger.enqueue(msg)
+	}
+
+	return firstErr
+}
+
+// LogWithLevel sends a log message to every destination with a parameterized level.
+func (ml *MultiLogger) LogWithLevel(message string, level string) error {
+	options := Options{Level: level}
+	return ml.LogWithOptions(message, options)
+}
+
+// Info logs a message at level Info to every destination.
+func (ml *MultiLogger) Info(message string) {
+	ml.LogWithLevel(message, "info")
+}
+
+// Warn logs a message at level Warn to every destination.
+func (ml *MultiLogger) Warn(message string) {
+	ml.LogWithLevel(message, "warn")
+}
+
+// Debug logs a message at level Debug to every destination.
+func (ml *MultiLogger) Debug(message string) {
+	ml.LogWithLevel(message, "debug")
+}
+
+// Error logs a message at level Error to every destination.
+func (ml *MultiLogger) Error(message string) {
+	ml.LogWithLevel(message, "error")
+}
+
+// Fatal logs a message at level Fatal to every destination. When any
+// destination has Options.ExitOnFatal set, every destination is then
+// flushed and the process exits.
+func (ml *MultiLogger) Fatal(message string) {
+	ml.LogWithLevel(message, "fatal")
+	ml.exitFatal()
+}
+
+// exitFatal flushes every destination for up to its own
+// Options.FatalFlushTimeout and exits through the Options.ExitFunc of the
+// first destination with Options.ExitOnFatal set.
+func (ml *MultiLogger) exitFatal() {
+	var exiting *Logger
+	for _, d := range ml.destinations {
+		if d.logger.Options.ExitOnFatal {
+			exiting = d.logger
+			break
+		}
+	}
+	if exiting == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range ml.destinations {
+		wg.Add(1)
+		go func(l *Logger) {
+			l.Flush(l.Options.FatalFlushTimeout)
+			wg.Done()
+		}(d.logger)
+	}
+	wg.Wait()
+
+	exit := exiting.Options.ExitFunc
+	if exit == nil {
+		exit = os.Exit
+	}
+	exit(1)
+}
+
+// Critical logs a message at level Critical to every destination.
+func (ml *MultiLogger) Critical(message string) {
+	ml.LogWithLevel(message, "critical")
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiLogger_NewMultiLogger(t *testing.T) {
+	t.Run("Base", func(t *testing.T) {
+		ml, err := NewMultiLogger([]Destination{
+			{Key: "abc123", Options: Options{App: "one"}},
+			{Key: "def456", Options: Options{App: "two"}},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(ml.destinations))
+		ml.Close()
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := NewMultiLogger([]Destination{
+			{Key: "abc123"},
+			{Key: "def456", Options: Options{App: strings.Repeat("a", 83)}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestMultiLogger_Log(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string][]interface{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		key := body["apikey"].(string)
+		bodies[key] = append(bodies[key], body["lines"].([]interface{})...)
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	ml, err := NewMultiLogger([]Destination{
+		{Key: "main", Options: Options{IngestURL: ts.URL, App: "main"}},
+		{Key: "security", Options: Options{IngestURL: ts.URL, App: "security"}, Filter: LevelFilter("error")},
+		{Key: "broken", Options: Options{IngestURL: failing.URL}},
+	})
+	assert.Equal(t, nil, err)
+
+	ml.Info("testing")
+	ml.Error("testing")
+	ml.Close()
+
+	assert.Equal(t, 2, len(bodies["main"]))
+	if assert.Equal(t, 1, len(bodies["security"])) {
+		line := bodies["security"][0].(map[string]interface{})
+		assert.Equal(t, "error", line["level"])
+		assert.Equal(t, "security", line["app"])
+	}
+}
+
+func TestMultiLogger_LogWithOptions(t *testing.T) {
+	ml, err := NewMultiLogger([]Destination{{Key: "abc123"}})
+	assert.Equal(t, nil, err)
+	defer ml.Close()
+
+	err = ml.LogWithOptions("testing", Options{
+		App: strings.Repeat("a", 83),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelSpecEnv is the environment variable from which Options.LevelSpec
+// is read when it is not set.
+const LevelSpecEnv = "LOGDNA_LEVELS"
+
+// nameField is the metadata field holding the name of a named logger.
+const nameField = "logger"
+
+// LevelSpec assigns minimum levels to named loggers by name prefix.
+type LevelSpec struct {
+	// Default is the level set by the "*" entry, or 0 when there is none.
+	Default Level
+	rules   []levelRule
+}
+
+type levelRule struct {
+	prefix string
+	level  Level
+}
+
+// ParseLevelSpec parses a comma-separated list of name=level entries, such
+// as "db=debug,http=warn,*=info". An entry applies to the logger with that
+// name and all loggers below it, with the longest matching name taking
+// precedence. The "*" entry applies to all other loggers.
+func ParseLevelSpec(spec string) (LevelSpec, error) {
+	var ls LevelSpec
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return LevelSpec{}, fmt.Errorf("invalid level spec entry: %q", entry)
+		}
+
+		level, err := ParseLevel(parts[1])
+		if err != nil {
+			return LevelSpec{}, err
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if name == "*" {
+			ls.Default = level
+			continue
+		}
+		ls.rules = append(ls.rules, levelRule{prefix: name, level: level})
+	}
+
+	return ls, nil
+}
+
+// lookup returns the level for the named logger, and whether any entry
+// other than "*" matched.
+func (ls LevelSpec) lookup(name string) (Level, bool) {
+	var match levelRule
+	for _, rule := range ls.rules {
+		if name != rule.prefix && !strings.HasPrefix(name, rule.prefix+".") {
+			continue
+		}
+		if len(rule.prefix) > len(match.prefix) {
+			match = rule
+		}
+	}
+	return match.level, match.prefix != ""
+}
+
+// levelSpecHolder allows a LevelSpec shared by a tree of loggers to be
+// replaced while messages are being logged.
+type levelSpecHolder struct {
+	v atomic.Value
+}
+
+func (h *levelSpecHolder) load() LevelSpec {
+	ls, _ := h.v.Load().(LevelSpec)
+	return ls
+}
+
+// Named returns a child logger named after its parent's name and the given
+// name, separated by a dot, such as "db.pool". The full name is added to
+// the metadata of every message under the "logger" field, or used as the
+// App when Options.NameAsApp is set, and selects the minimum level from
+// Options.LevelSpec.
+func (l *Logger) Named(name string) *Logger {
+	child := l.newChild()
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	child.name = name
+
+	if l.Options.NameAsApp {
+		child.Options.App = name
+	} else {
+		child.Options.Fields = mergeFields(l.Options.Fields, Fields{nameField: name})
+	}
+	return child
+}
+
+// Name returns the name of the logger, or an empty string for loggers
+// which are not named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetLevelSpec replaces the level spec of the logger and all loggers sharing
+// its transport. The "*" entry, when present, also sets the minimum level
+// used by loggers matching no other entry, as with SetLevel.
+func (l *Logger) SetLevelSpec(spec string) error {
+	ls, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	l.applyLevelSpec(ls)
+	return nil
+}
+
+func (l *Logger) applyLevelSpec(ls LevelSpec) {
+	l.levelSpec.v.Store(ls)
+	if ls.Default != 0 {
+		l.level.SetLevel(ls.Default)
+	}
+}
+
+// minLevel returns the minimum level of the logger, taking its name into
+// account.
+func (l *Logger) minLevel() Level {
+	if l.name != "" {
+		if level, ok := l.levelSpec.load().lookup(l.name); ok {
+			return level
+		}
+	}
+	return l.level.Level()
+}
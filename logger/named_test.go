@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamed_ParseLevelSpec(t *testing.T) {
+	ls, err := ParseLevelSpec(" db=debug, db.pool=error,http=WARNING,*=info ")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, InfoLevel, ls.Default)
+
+	testCases := []struct {
+		name  string
+		level Level
+		ok    bool
+	}{
+		{"db", DebugLevel, true},
+		{"db.conn", DebugLevel, true},
+		{"db.pool", ErrorLevel, true},
+		{"db.pool.stats", ErrorLevel, true},
+		{"dbx", 0, false},
+		{"http", WarnLevel, true},
+		{"cache", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			level, ok := ls.lookup(tc.name)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.level, level)
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := ParseLevelSpec("db")
+		assert.Error(t, err)
+		_, err = ParseLevelSpec("db=verbose")
+		assert.Error(t, err)
+		_, err = ParseLevelSpec("=debug")
+		assert.Error(t, err)
+	})
+}
+
+func TestLogger_Named(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		IndexMeta: true,
+		LevelSpec: "db=debug,http=warn,*=info",
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	pool := l.Named("db").Named("pool")
+	web := l.Named("http")
+	cache := l.Named("cache")
+
+	assert.Equal(t, "db.pool", pool.Name())
+	assert.Equal(t, DebugLevel, pool.Level())
+	assert.Equal(t, WarnLevel, web.Level())
+	assert.Equal(t, InfoLevel, cache.Level())
+
+	pool.Debug("pool")
+	web.Info("dropped")
+	cache.Debug("dropped")
+	cache.Info("cache")
+
+	// SetLevelSpec applies to the existing loggers
+	assert.Equal(t, nil, l.SetLevelSpec("http=debug"))
+	web.Debug("http")
+
+	assert.Error(t, l.SetLevelSpec("http"))
+	l.Close()
+
+	if assert.Equal(t, 3, len(lines)) {
+		for i, name := range []string{"db.pool", "cache", "http"} {
+			line := lines[i].(map[string]interface{})
+			assert.Equal(t, name, line["meta"].(map[string]interface{})["logger"])
+		}
+	}
+}
+
+func TestLogger_NameAsApp(t *testing.T) {
+	l, err := NewLogger(Options{NameAsApp: true}, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	child := l.Named("db").Named("pool")
+	assert.Equal(t, "db.pool", child.Options.App)
+	assert.Nil(t, child.Options.Fields)
+}
+
+func TestLogger_LevelSpecEnv(t *testing.T) {
+	os.Setenv(LevelSpecEnv, "db=error")
+	defer os.Unsetenv(LevelSpecEnv)
+
+	l, err := NewLogger(Options{}, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	assert.Equal(t, ErrorLevel, l.Named("db").Level())
+
+	t.Run("Invalid", func(t *testing.T) {
+		os.Setenv(LevelSpecEnv, "db")
+		l, msgs := captureLogger(t, Options{})
+		defer l.Close()
+
+		assert.Equal(t, "", l.Options.LevelSpec)
+		assert.Equal(t, Level(0), l.Named("db").Level())
+		if assert.Equal(t, 1, len(*msgs)) {
+			assert.Equal(t, "warn", (*msgs)[0].Options.Level)
+			assert.Contains(t, (*msgs)[0].Body, "Ignoring invalid "+LevelSpecEnv)
+		}
+
+		// an explicit spec is still rejected
+		_, err := NewLogger(Options{LevelSpec: "db"}, "abc123")
+		assert.EqualError(t, err, "One or more invalid options:\nLevelSpec: Invalid format\n")
+	})
+}
@@ -9,11 +9,18 @@ import (
 )
 
 const (
-	defaultIngestURL     = "https://logs.logdna.com/logs/ingest"
-	defaultSendTimeout   = 30 * time.Second
-	defaultFlushInterval = 250 * time.Millisecond
-	defaultMaxBufferLen  = 50
-	maxOptionLength      = 80
+	defaultIngestURL           = "https://logs.logdna.com/logs/ingest"
+	defaultSendTimeout         = 30 * time.Second
+	defaultFlushInterval       = 250 * time.Millisecond
+	defaultMaxBufferLen        = 50
+	defaultMaxFlushInterval    = 5 * time.Second
+	defaultMaxBatchBytes       = 2 * 1024 * 1024
+	defaultRouteIdleTimeout    = 5 * time.Minute
+	defaultArchiveSegmentBytes = 10 * 1024 * 1024
+	defaultMaxRetries          = 3
+	defaultRetryBackoff        = time.Second
+	maxAdaptiveBufferLen       = 5000
+	maxOptionLength            = 80
 )
 
 // InvalidOptionMessage represents an issue with the supplied configuration.
@@ -25,20 +32,48 @@ type InvalidOptionMessage struct {
 // Options encapsulates user-provided options such as the Level and App
 // that are passed along with each log.
 type Options struct {
-	App           string
-	Env           string
-	FlushInterval time.Duration
-	SendTimeout   time.Duration
-	Hostname      string
-	IndexMeta     bool
-	IngestURL     string
-	IPAddress     string
-	Level         string
-	MacAddress    string
-	MaxBufferLen  int
-	Meta          string
-	Tags          string
-	Timestamp     time.Time
+	AdaptiveBatching    bool
+	App                 string
+	ArchiveDir          string
+	ArchiveSegmentBytes int
+	BlockOnMemoryLimit  bool
+	CallerAsFile        bool
+	CallerSkip          int
+	Dedup               *Dedup
+	Env                 string
+	ExitFunc            func(int)
+	ExitOnFatal         bool
+	FatalFlushTimeout   time.Duration
+	Fields              Fields
+	FlushInterval       time.Duration
+	Hooks               []Hook
+	SendTimeout         time.Duration
+	Hostname            string
+	IndexMeta           bool
+	IngestURL           string
+	IPAddress           string
+	Level               string
+	LevelSpec           string
+	LongLines           LongLinePolicy
+	MacAddress          string
+	MaxBatchBytes       int
+	MaxBufferLen        int
+	MaxFlushInterval    time.Duration
+	MaxLineBytes        int
+	MaxMemoryBytes      int
+	MaxMetaBytes        int
+	MaxRetries          int
+	Meta                string
+	MinLevel            Level
+	NameAsApp           bool
+	Redaction           *Redaction
+	ReportCaller        bool
+	RetryBackoff        time.Duration
+	Router              Router
+	RouteIdleTimeout    time.Duration
+	Sampling            *Sampling
+	Tags                string
+	Timestamp           time.Time
 }
 
 type fieldIssue struct {
@@ -66,8 +101,9 @@ func validateOptionLength(option string, value string) *fieldIssue {
 	return nil
 }
 
+var reHostname = regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9-]*[A-Za-z0-9])$`)
+
 func (options *Options) validate() error {
-	reHostname := regexp.MustCompile(`^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9-]*[A-Za-z0-9])$`)
 	var issues []fieldIssue
 
 	if issue := validateOptionLength("App", options.App); issue != nil {
@@ -112,12 +148,33 @@ func (options Options) merge(merge Options) Options {
 	if merge.Env != "" {
 		newOpts.Env = merge.Env
 	}
+	if merge.Fields != nil {
+		newOpts.Fields = mergeFields(options.Fields, merge.Fields)
+	}
+	if merge.Hostname != "" {
+		newOpts.Hostname = merge.Hostname
+	}
+	if merge.IndexMeta {
+		newOpts.IndexMeta = true
+	}
+	if merge.IPAddress != "" {
+		newOpts.IPAddress = merge.IPAddress
+	}
 	if merge.Level != "" {
 		newOpts.Level = merge.Level
 	}
+	if merge.MacAddress != "" {
+		newOpts.MacAddress = merge.MacAddress
+	}
 	if merge.Meta != "" {
 		newOpts.Meta = merge.Meta
 	}
+	if merge.Tags != "" {
+		newOpts.Tags = merge.Tags
+	}
+	if !merge.Timestamp.IsZero() {
+		newOpts.Timestamp = merge.Timestamp
+	}
 
 	return newOpts
 }
@@ -135,4 +192,34 @@ func (options *Options) setDefaults() {
 	if options.MaxBufferLen == 0 {
 		options.MaxBufferLen = defaultMaxBufferLen
 	}
+	if options.MaxFlushInterval == 0 {
+		options.MaxFlushInterval = defaultMaxFlushInterval
+	}
+	if options.MaxFlushInterval < options.FlushInterval {
+		options.MaxFlushInterval = options.FlushInterval
+	}
+	if options.MaxBatchBytes == 0 {
+		options.MaxBatchBytes = defaultMaxBatchBytes
+	}
+	if options.MaxLineBytes == 0 {
+		options.MaxLineBytes = defaultMaxLineBytes
+	}
+	if options.MaxMetaBytes == 0 {
+		options.MaxMetaBytes = defaultMaxMetaBytes
+	}
+	if options.ArchiveSegmentBytes == 0 {
+		options.ArchiveSegmentBytes = defaultArchiveSegmentBytes
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = defaultMaxRetries
+	}
+	if options.RetryBackoff == 0 {
+		options.RetryBackoff = defaultRetryBackoff
+	}
+	if options.FatalFlushTimeout == 0 {
+		options.FatalFlushTimeout = defaultFatalFlushTimeout
+	}
+	if options.RouteIdleTimeout == 0 {
+		options.RouteIdleTimeout = defaultRouteIdleTimeout
+	}
 }
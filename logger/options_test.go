@@ -40,23 +40,39 @@ func TestOptions_Validate(t *testing.T) {
 
 func TestOptions_Merge(t *testing.T) {
 	o := Options{
-		App:   "app",
-		Env:   "development",
-		Level: "info",
-		Meta:  `{"foo": "bar"}`,
+		App:    "app",
+		Env:    "development",
+		Level:  "info",
+		Meta:   `{"foo": "bar"}`,
+		Fields: Fields{"foo": "bar"},
 	}
 
+	now := time.Now()
 	o = o.merge(Options{
-		App:   "merge",
-		Env:   "merge",
-		Level: "merge",
-		Meta:  `{"baz": "merge"}`,
+		App:        "merge",
+		Env:        "merge",
+		Hostname:   "merge",
+		IndexMeta:  true,
+		IPAddress:  "10.0.0.1",
+		Level:      "merge",
+		MacAddress: "c0:ff:ee:c0:ff:ee",
+		Meta:       `{"baz": "merge"}`,
+		Fields:     Fields{"baz": "merge"},
+		Tags:       "merge",
+		Timestamp:  now,
 	})
 
 	assert.Equal(t, "merge", o.App)
 	assert.Equal(t, "merge", o.Env)
 	assert.Equal(t, "merge", o.Level)
 	assert.Equal(t, `{"baz": "merge"}`, o.Meta)
+	assert.Equal(t, Fields{"foo": "bar", "baz": "merge"}, o.Fields)
+	assert.Equal(t, "merge", o.Hostname)
+	assert.True(t, o.IndexMeta)
+	assert.Equal(t, "10.0.0.1", o.IPAddress)
+	assert.Equal(t, "c0:ff:ee:c0:ff:ee", o.MacAddress)
+	assert.Equal(t, "merge", o.Tags)
+	assert.Equal(t, now, o.Timestamp)
 }
 
 func TestOptions_SetDefaults(t *testing.T) {
@@ -70,6 +86,8 @@ func TestOptions_SetDefaults(t *testing.T) {
 		assert.Equal(t, defaultFlushInterval, o.FlushInterval)
 		assert.Equal(t, defaultMaxBufferLen, o.MaxBufferLen)
 		assert.Equal(t, defaultIngestURL, o.IngestURL)
+		assert.Equal(t, defaultMaxFlushInterval, o.MaxFlushInterval)
+		assert.Equal(t, defaultMaxBatchBytes, o.MaxBatchBytes)
 	})
 
 	t.Run("Retains existing values", func(t *testing.T) {
@@ -83,5 +101,6 @@ func TestOptions_SetDefaults(t *testing.T) {
 		assert.Equal(t, 10*time.Second, o.FlushInterval)
 		assert.Equal(t, 10, o.MaxBufferLen)
 		assert.Equal(t, "https://example.org", o.IngestURL)
+		assert.Equal(t, 10*time.Second, o.MaxFlushInterval)
 	})
 }
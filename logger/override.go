@@ -0,0 +1,122 @@
+package logger
+
+import "time"
+
+// MessageOption sets or clears an option for a single message. Options
+// which are not mentioned are inherited from the logger.
+type MessageOption func(*Options)
+
+// Set overrides the options of a message with the non-empty fields of
+// options, as with LogWithOptions.
+func Set(options Options) MessageOption {
+	return func(o *Options) {
+		*o = o.merge(options)
+	}
+}
+
+// SetIndexMeta sets whether the metadata of a message is searchable,
+// which unlike Set can also turn off IndexMeta.
+func SetIndexMeta(indexed bool) MessageOption {
+	return func(o *Options) {
+		o.IndexMeta = indexed
+	}
+}
+
+// SetTimestamp sets the timestamp of a message.
+func SetTimestamp(t time.Time) MessageOption {
+	return func(o *Options) {
+		o.Timestamp = t
+	}
+}
+
+// UnsetApp clears the app name of a message.
+func UnsetApp() MessageOption {
+	return func(o *Options) {
+		o.App = ""
+	}
+}
+
+// UnsetEnv clears the environment of a message.
+func UnsetEnv() MessageOption {
+	return func(o *Options) {
+		o.Env = ""
+	}
+}
+
+// UnsetFields clears the structured metadata of a message.
+func UnsetFields() MessageOption {
+	return func(o *Options) {
+		o.Fields = nil
+	}
+}
+
+// UnsetHostname clears the hostname of a message.
+func UnsetHostname() MessageOption {
+	return func(o *Options) {
+		o.Hostname = ""
+	}
+}
+
+// UnsetIPAddress clears the IP address of a message.
+func UnsetIPAddress() MessageOption {
+	return func(o *Options) {
+		o.IPAddress = ""
+	}
+}
+
+// UnsetLevel clears the level of a message.
+func UnsetLevel() MessageOption {
+	return func(o *Options) {
+		o.Level = ""
+	}
+}
+
+// UnsetMacAddress clears the MAC address of a message.
+func UnsetMacAddress() MessageOption {
+	return func(o *Options) {
+		o.MacAddress = ""
+	}
+}
+
+// UnsetMeta clears the raw metadata of a message.
+func UnsetMeta() MessageOption {
+	return func(o *Options) {
+		o.Meta = ""
+	}
+}
+
+// UnsetTags clears the tags of a message.
+func UnsetTags() MessageOption {
+	return func(o *Options) {
+		o.Tags = ""
+	}
+}
+
+// UnsetTimestamp clears the timestamp of a message, so that the time it is
+// sent is used.
+func UnsetTimestamp() MessageOption {
+	return func(o *Options) {
+		o.Timestamp = time.Time{}
+	}
+}
+
+// LogWithMessageOptions sends a log message to LogDNA with the logger's
+// options changed by opts, which are applied in order. The resulting
+// options are validated before the message is sent.
+func (l *Logger) LogWithMessageOptions(message string, opts ...MessageOption) error {
+	msgOpts := l.Options
+	for _, opt := range opts {
+		opt(&msgOpts)
+	}
+	if !l.enabled(msgOpts.Level) {
+		return nil
+	}
+
+	err := msgOpts.validate()
+	if err != nil {
+		return err
+	}
+
+	l.enqueue(Message{Body: message, Options: msgOpts})
+	return nil
+}
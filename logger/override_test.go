@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_LogWithMessageOptions(t *testing.T) {
+	var line map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&body)
+		line = body["lines"].([]interface{})[0].(map[string]interface{})
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		App:       "app",
+		Env:       "production",
+		IndexMeta: true,
+		Meta:      `{"foo":"bar"}`,
+	}
+
+	t.Run("Set and unset", func(t *testing.T) {
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+
+		err = l.LogWithMessageOptions("testing",
+			Set(Options{App: "other", Level: "warn"}),
+			UnsetEnv(),
+			UnsetMeta(),
+		)
+		assert.Equal(t, nil, err)
+		l.Close()
+
+		assert.Equal(t, "other", line["app"])
+		assert.Equal(t, "warn", line["level"])
+		assert.Nil(t, line["env"])
+		assert.Empty(t, line["meta"])
+	})
+
+	t.Run("Index meta off", func(t *testing.T) {
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+
+		err = l.LogWithMessageOptions("testing", SetIndexMeta(false))
+		assert.Equal(t, nil, err)
+		l.Close()
+
+		// inherited from the logger
+		assert.Equal(t, "app", line["app"])
+		assert.Equal(t, `{"foo":"bar"}`, line["meta"])
+	})
+
+	t.Run("Timestamp", func(t *testing.T) {
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+
+		ts := time.Date(2020, 6, 2, 10, 0, 0, 0, time.UTC)
+		err = l.LogWithMessageOptions("testing", SetTimestamp(ts), UnsetApp(), UnsetTags())
+		assert.Equal(t, nil, err)
+		l.Close()
+
+		assert.Equal(t, float64(ts.UnixNano()/int64(time.Millisecond)), line["timestamp"])
+		assert.Nil(t, line["app"])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		l, err := NewLogger(o, "abc123")
+		assert.Equal(t, nil, err)
+		defer l.Close()
+
+		err = l.LogWithMessageOptions("testing", Set(Options{Hostname: "-"}))
+		assert.EqualError(t, err, "One or more invalid options:\nHostname: Invalid format\n")
+	})
+}
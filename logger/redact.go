@@ -0,0 +1,270 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// Detector finds sensitive values in text. Every match of Pattern for which
+// Validate, when set, returns true is redacted.
+type Detector struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// Built-in detectors for common kinds of sensitive data.
+var (
+	EmailDetector = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+	CreditCardDetector = Detector{
+		Name:     "credit_card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: luhnValid,
+	}
+	JWTDetector = Detector{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	}
+	BearerTokenDetector = Detector{
+		Name:    "bearer_token",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`),
+	}
+	AWSKeyDetector = Detector{
+		Name:    "aws_key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}
+	IPDetector = Detector{
+		Name:    "ip",
+		Pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}\b`),
+		Validate: func(match string) bool {
+			return net.ParseIP(match) != nil
+		},
+	}
+)
+
+// Redaction configures the scrubbing of sensitive data from messages. It
+// applies to message bodies, Meta and Fields before messages are buffered,
+// so redacted values are never archived or sent.
+type Redaction struct {
+	// Detectors find sensitive values in bodies and metadata values.
+	Detectors []Detector
+	// Patterns are custom expressions whose matches are redacted.
+	Patterns []*regexp.Regexp
+	// SensitiveKeys are metadata keys, compared case-insensitively, whose
+	// values are redacted entirely.
+	SensitiveKeys []string
+	// HashSensitiveKeys replaces the values of sensitive keys with their
+	// SHA-256 hash instead of the mask, so equal values can still be
+	// correlated.
+	HashSensitiveKeys bool
+	// Mask replaces redacted values, "[REDACTED]" by default.
+	Mask string
+}
+
+type redactor struct {
+	detectors     []Detector
+	sensitiveKeys map[string]bool
+	hash          bool
+	mask          string
+}
+
+func newRedactor(options Redaction) *redactor {
+	r := &redactor{
+		detectors:     append([]Detector(nil), options.Detectors...),
+		sensitiveKeys: make(map[string]bool),
+		hash:          options.HashSensitiveKeys,
+		mask:          options.Mask,
+	}
+	if r.mask == "" {
+		r.mask = defaultRedactionMask
+	}
+	for _, pattern := range options.Patterns {
+		r.detectors = append(r.detectors, Detector{Name: "custom", Pattern: pattern})
+	}
+	for _, key := range options.SensitiveKeys {
+		r.sensitiveKeys[strings.ToLower(key)] = true
+	}
+	return r
+}
+
+// redact scrubs the body and metadata of the message.
+func (r *redactor) redact(msg *Message) {
+	msg.Body = r.redactString(msg.Body)
+	if msg.Options.Meta != "" {
+		msg.Options.Meta = r.redactMeta(msg.Options.Meta)
+	}
+	if len(msg.Options.Fields) > 0 {
+		msg.Options.Fields = Fields(r.redactMap(msg.Options.Fields))
+	}
+}
+
+func (r *redactor) redactString(s string) string {
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.Validate != nil && !d.Validate(match) {
+				return match
+			}
+			return r.mask
+		})
+	}
+	return s
+}
+
+// redactMeta scrubs raw Meta, applying the sensitive keys when it holds
+// JSON and treating it as text otherwise.
+func (r *redactor) redactMeta(meta string) string {
+	decoded, err := decodeJSON([]byte(meta))
+	if err != nil {
+		return r.redactString(meta)
+	}
+
+	encoded, err := json.Marshal(r.redactValue(decoded))
+	if err != nil {
+		return r.redactString(meta)
+	}
+	return string(encoded)
+}
+
+// redactMap returns a redacted copy, leaving the original untouched as it
+// may be shared with the logger.
+func (r *redactor) redactMap(m map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if r.sensitiveKeys[strings.ToLower(k)] {
+			redacted[k] = r.redactSensitive(v)
+			continue
+		}
+		redacted[k] = r.redactValue(v)
+	}
+	return redacted
+}
+
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.redactString(value)
+	case Fields:
+		return Fields(r.redactMap(value))
+	case map[string]interface{}:
+		return r.redactMap(value)
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, e := range value {
+			redacted[i] = r.redactValue(e)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(value))
+		for i, e := range value {
+			redacted[i] = r.redactString(e)
+		}
+		return redacted
+	case json.Number:
+		return r.redactNumber(v, string(value))
+	case nil:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.redactNumber(v, strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.redactNumber(v, strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Bool, reflect.Float32, reflect.Float64:
+		return v
+	}
+	return r.redactEncoded(v)
+}
+
+// redactNumber replaces a number with the mask when its decimal form
+// matches a detector, such as a card number stored as an integer.
+func (r *redactor) redactNumber(v interface{}, decimal string) interface{} {
+	if r.redactString(decimal) != decimal {
+		return r.mask
+	}
+	return v
+}
+
+// redactEncoded redacts any other value, such as a struct, through the
+// generic form of its JSON encoding, which is what would be sent.
+func (r *redactor) redactEncoded(v interface{}) interface{} {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return r.redactString(fmt.Sprintf("%+v", v))
+	}
+
+	decoded, err := decodeJSON(encoded)
+	if err != nil {
+		return r.redactString(string(encoded))
+	}
+	return r.redactValue(decoded)
+}
+
+// decodeJSON decodes into generic values, keeping numbers as json.Number
+// so that they are encoded again unchanged.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return decoded, nil
+}
+
+func (r *redactor) redactSensitive(v interface{}) interface{} {
+	if !r.hash {
+		return r.mask
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return r.mask
+	}
+	sum := sha256.Sum256(encoded)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used
+// by payment card numbers.
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
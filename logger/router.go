@@ -0,0 +1,39 @@
+package logger
+
+import "encoding/json"
+
+// Route describes where a message is sent. Empty fields fall back to
+// the logger's ingestion key and the message's own options.
+type Route struct {
+	Key  string
+	App  string
+	Tags string
+}
+
+// Router chooses the Route for each message. Messages sharing a key and
+// tags are batched together, while all routes share the logger's flush
+// interval, buffer and memory limits.
+type Router func(Message) Route
+
+// MetaFieldRouter returns a Router which looks up the value of the given
+// field of each message's Fields, or else its Meta, in routes. Messages
+// without the field, or with a value missing from routes, use the
+// logger's defaults.
+func MetaFieldRouter(field string, routes map[string]Route) Router {
+	return func(msg Message) Route {
+		if value, ok := msg.Options.Fields[field].(string); ok {
+			return routes[value]
+		}
+
+		var meta map[string]interface{}
+		if err := json.Unmarshal([]byte(msg.Options.Meta), &meta); err != nil {
+			return Route{}
+		}
+
+		value, ok := meta[field].(string)
+		if !ok {
+			return Route{}
+		}
+		return routes[value]
+	}
+}
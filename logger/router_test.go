@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_MetaFieldRouter(t *testing.T) {
+	router := MetaFieldRouter("tenant", map[string]Route{
+		"acme": {Key: "acme-key", App: "acme"},
+	})
+
+	testCases := []struct {
+		label string
+		meta  string
+		route Route
+	}{
+		{"Known value", `{"tenant": "acme"}`, Route{Key: "acme-key", App: "acme"}},
+		{"Unknown value", `{"tenant": "other"}`, Route{}},
+		{"Missing field", `{"foo": "bar"}`, Route{}},
+		{"Invalid meta", `not json`, Route{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			msg := Message{Options: Options{Meta: tc.meta}}
+			assert.Equal(t, tc.route, router(msg))
+		})
+	}
+}
+
+func TestLogger_Router(t *testing.T) {
+	var mu sync.Mutex
+	payloads := make(map[string]map[string]interface{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		payloads[r.Header.Get("apikey")] = body
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		App:       "platform",
+		Router: func(msg Message) Route {
+			if msg.Body == "acme" {
+				return Route{Key: "acme-key", App: "acme", Tags: "tenant"}
+			}
+			return Route{}
+		},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	l.Log("acme")
+	l.Log("platform")
+	l.Log("acme")
+	l.Close()
+
+	assert.Equal(t, 2, len(payloads))
+
+	acme := payloads["acme-key"]
+	if assert.NotEmpty(t, acme) {
+		assert.Equal(t, "acme-key", acme["apikey"])
+		assert.Equal(t, "tenant", acme["tags"])
+
+		ls := acme["lines"].([]interface{})
+		assert.Equal(t, 2, len(ls))
+		assert.Equal(t, "acme", ls[0].(map[string]interface{})["app"])
+	}
+
+	platform := payloads["abc123"]
+	if assert.NotEmpty(t, platform) {
+		ls := platform["lines"].([]interface{})
+		assert.Equal(t, 1, len(ls))
+		assert.Equal(t, "platform", ls[0].(map[string]interface{})["app"])
+	}
+}
+
+func TestLogger_RouteIdleTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	fi := 20 * time.Millisecond
+	o := Options{
+		IngestURL:        ts.URL,
+		FlushInterval:    fi,
+		RouteIdleTimeout: fi,
+		Router: func(msg Message) Route {
+			return Route{Key: msg.Body}
+		},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+	defer l.Close()
+
+	l.Log("tenant-a")
+	l.Log("tenant-b")
+
+	l.transport.mu.Lock()
+	assert.Equal(t, 2, len(l.transport.batches))
+	l.transport.mu.Unlock()
+
+	time.Sleep(5 * fi)
+
+	l.transport.mu.Lock()
+	assert.Equal(t, 0, len(l.transport.batches))
+	l.transport.mu.Unlock()
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// sampledOutField is the metadata field holding the number of messages
+// dropped by sampling since the previous message with the same key.
+const sampledOutField = "sampled_out"
+
+const defaultSamplingInterval = time.Second
+
+// SamplingRule limits how many messages with the same key are sent in each
+// sampling interval: the First messages are sent, followed by every
+// Thereafter-th message. A rule with both values zero sends all messages.
+type SamplingRule struct {
+	First      int
+	Thereafter int
+}
+
+// Sampling configures the sampling of repetitive messages. Messages are
+// sampled after redaction and hooks, once their Lazy values are computed.
+type Sampling struct {
+	// Interval is the period after which the count of each key is reset,
+	// one second by default.
+	Interval time.Duration
+	// Default is the rule for levels missing from Levels.
+	Default SamplingRule
+	// Levels holds the rules for specific levels.
+	Levels map[Level]SamplingRule
+	// Key returns the key under which messages are counted. Messages are
+	// counted by level and body when it is nil.
+	Key func(Message) string
+}
+
+type sampler struct {
+	options Sampling
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]*sampleCount
+}
+
+type sampleCount struct {
+	seen    int
+	dropped int
+}
+
+func newSampler(options Sampling) *sampler {
+	if options.Interval <= 0 {
+		options.Interval = defaultSamplingInterval
+	}
+
+	return &sampler{
+		options: options,
+		counts:  make(map[string]*sampleCount),
+	}
+}
+
+func (s *sampler) rule(level string) SamplingRule {
+	if parsed, err := ParseLevel(level); err == nil {
+		if rule, ok := s.options.Levels[parsed]; ok {
+			return rule
+		}
+	}
+	return s.options.Default
+}
+
+// sample reports whether the message should be sent. When it is, the number
+// of messages with the same key dropped since the last one sent is added to
+// its metadata.
+func (s *sampler) sample(msg *Message) bool {
+	rule := s.rule(msg.Options.Level)
+	if rule.First == 0 && rule.Thereafter == 0 {
+		return true
+	}
+
+	key := msg.Options.Level + "\x00" + msg.Body
+	if s.options.Key != nil {
+		key = s.options.Key(*msg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.options.Interval {
+		s.resetWindow(now)
+	}
+
+	count, ok := s.counts[key]
+	if !ok {
+		count = &sampleCount{}
+		s.counts[key] = count
+	}
+	count.seen++
+
+	n := count.seen - rule.First
+	if n > 0 && (rule.Thereafter <= 0 || n%rule.Thereafter != 0) {
+		count.dropped++
+		return false
+	}
+
+	if count.dropped > 0 {
+		msg.Options.Fields = mergeFields(msg.Options.Fields, Fields{sampledOutField: count.dropped})
+		count.dropped = 0
+	}
+	return true
+}
+
+// resetWindow starts a new sampling interval. Only keys with dropped
+// messages which have not been reported yet are carried over.
+func (s *sampler) resetWindow(now time.Time) {
+	s.windowStart = now
+	for key, count := range s.counts {
+		if count.dropped == 0 {
+			delete(s.counts, key)
+			continue
+		}
+		count.seen = 0
+	}
+}
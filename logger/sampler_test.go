@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler_Sample(t *testing.T) {
+	s := newSampler(Sampling{
+		Interval: time.Hour,
+		Default:  SamplingRule{First: 2, Thereafter: 3},
+		Levels: map[Level]SamplingRule{
+			ErrorLevel: {},
+		},
+	})
+
+	var kept []int
+	for i := 1; i <= 10; i++ {
+		msg := Message{Body: "testing", Options: Options{Level: "info"}}
+		if s.sample(&msg) {
+			kept = append(kept, i)
+		}
+	}
+	// first 2, then every 3rd
+	assert.Equal(t, []int{1, 2, 5, 8}, kept)
+
+	t.Run("Reports sampled out messages", func(t *testing.T) {
+		msg := Message{Body: "testing", Options: Options{Level: "info"}}
+		for !s.sample(&msg) {
+		}
+		assert.Equal(t, 2, msg.Options.Fields[sampledOutField])
+	})
+
+	t.Run("Keys by level and body", func(t *testing.T) {
+		msg := Message{Body: "other", Options: Options{Level: "info"}}
+		assert.True(t, s.sample(&msg))
+		assert.Nil(t, msg.Options.Fields)
+	})
+
+	t.Run("Per-level rule", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			msg := Message{Body: "testing", Options: Options{Level: "error"}}
+			assert.True(t, s.sample(&msg))
+		}
+	})
+}
+
+func TestSampler_Interval(t *testing.T) {
+	s := newSampler(Sampling{
+		Interval: 20 * time.Millisecond,
+		Default:  SamplingRule{First: 1},
+		Key:      func(Message) string { return "key" },
+	})
+
+	first := Message{Body: "first"}
+	second := Message{Body: "second"}
+	assert.True(t, s.sample(&first))
+	assert.False(t, s.sample(&second))
+
+	time.Sleep(40 * time.Millisecond)
+
+	third := Message{Body: "third"}
+	assert.True(t, s.sample(&third))
+	assert.Equal(t, 1, third.Options.Fields[sampledOutField])
+}
+
+func TestLogger_Sampling(t *testing.T) {
+	var lines []interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string](interface{}))
+		json.NewDecoder(r.Body).Decode(&body)
+		lines = append(lines, body["lines"].([]interface{})...)
+		json.NewEncoder(w).Encode(ingestAPIResponse{status: "ok"})
+	}))
+	defer ts.Close()
+
+	o := Options{
+		IngestURL: ts.URL,
+		Sampling: &Sampling{
+			Interval: time.Hour,
+			Default:  SamplingRule{First: 1, Thereafter: 10},
+		},
+	}
+
+	l, err := NewLogger(o, "abc123")
+	assert.Equal(t, nil, err)
+
+	for i := 0; i < 100; i++ {
+		l.Info("testing")
+	}
+	l.Close()
+
+	assert.Equal(t, 10, len(lines))
+}
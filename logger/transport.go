@@ -3,6 +3,7 @@ package logger
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,26 +11,83 @@ import (
 	"time"
 )
 
+// lineOverhead approximates the bytes a Line adds to the payload
+// beyond the variable-length fields of its Message.
+const lineOverhead = 64
+
 type transport struct {
-	key     string
-	buffer  []Message
-	options Options
-	done    chan struct{}
+	key         string
+	batches     map[batchKey]*batch
+	bufferBytes int
+	options     Options
+	archive     *archive
+	done        chan struct{}
+
+	// flushInterval and maxBufferLen are the effective batching settings,
+	// which only differ from options when AdaptiveBatching is enabled.
+	flushInterval time.Duration
+	maxBufferLen  int
+
+	// inflightBytes counts batches handed off for sending which have not
+	// completed yet. Together with bufferBytes it is checked against
+	// Options.MaxMemoryBytes.
+	inflightBytes int
+	dropped       uint64
+	released      *sync.Cond
 
 	mu sync.Mutex
 	wg sync.WaitGroup
 }
 
+// batchKey identifies the messages which can be sent in a single Payload,
+// as they share its payload-level attributes.
+type batchKey struct {
+	key        string
+	hostname   string
+	ipAddress  string
+	macAddress string
+	tags       string
+}
+
+type batch struct {
+	msgs     []Message
+	bytes    int
+	lastUsed time.Time
+}
+
+// BatchSettings describes the batching limits currently in effect
+// for a Logger.
+type BatchSettings struct {
+	FlushInterval time.Duration
+	MaxBufferLen  int
+	MaxBatchBytes int
+}
+
+// MemoryUsage reports the approximate memory held by a Logger for lines
+// that are buffered or being sent.
+type MemoryUsage struct {
+	Used    int
+	Limit   int
+	Dropped uint64
+}
+
 func newTransport(options Options, key string) *transport {
-	t := transport{
-		key:     key,
-		options: options,
-		done:    make(chan struct{}),
+	t := &transport{
+		key:           key,
+		batches:       make(map[batchKey]*batch),
+		options:       options,
+		done:          make(chan struct{}),
+		flushInterval: options.FlushInterval,
+		maxBufferLen:  options.MaxBufferLen,
+	}
+	t.released = sync.NewCond(&t.mu)
+	if options.ArchiveDir != "" {
+		t.archive = newArchive(options.ArchiveDir, options.ArchiveSegmentBytes)
 	}
 
-	go t.flushInterval()
+	go t.flushLoop(options.FlushInterval)
 
-	return &t
+	return t
 }
 
 func (t *transport) close() {
@@ -37,16 +95,165 @@ func (t *transport) close() {
 
 	close(t.done)
 	t.wg.Wait()
+
+	if t.archive != nil {
+		t.archive.close()
+	}
 }
 
+// add applies the line size limits to the message and buffers the
+// resulting messages.
 func (t *transport) add(msg Message) {
+	for _, m := range limitLine(msg, t.options) {
+		t.buffer(m)
+	}
+}
+
+func (t *transport) buffer(msg Message) {
+	bk := t.route(&msg)
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	t.buffer = append(t.buffer, msg)
+	size := msg.size()
+	if !t.reserve(size) {
+		t.dropped++
+		return
+	}
+
+	b, ok := t.batches[bk]
+	if !ok {
+		b = &batch{}
+		t.batches[bk] = b
+	}
+	b.msgs = append(b.msgs, msg)
+	b.bytes += size
+	b.lastUsed = time.Now()
+	t.bufferBytes += size
+
+	if len(b.msgs) >= t.maxBufferLen || b.bytes >= t.options.MaxBatchBytes {
+		t.adapt(true)
+		t.flushBatch(bk, b)
+	}
+}
 
-	if len(t.buffer) >= t.options.MaxBufferLen {
+// route applies Options.Router to the message and returns the key of the
+// batch it belongs to.
+func (t *transport) route(msg *Message) batchKey {
+	bk := batchKey{
+		key:        t.key,
+		hostname:   msg.Options.Hostname,
+		ipAddress:  msg.Options.IPAddress,
+		macAddress: msg.Options.MacAddress,
+		tags:       msg.Options.Tags,
+	}
+	if t.options.Router == nil {
+		return bk
+	}
+
+	r := t.options.Router(*msg)
+	if r.Key != "" {
+		bk.key = r.Key
+	}
+	if r.App != "" {
+		msg.Options.App = r.App
+	}
+	if r.Tags != "" {
+		msg.Options.Tags = r.Tags
+		bk.tags = r.Tags
+	}
+	return bk
+}
+
+// reserve reports whether a message of the given size fits in the memory
+// budget. When Options.BlockOnMemoryLimit is set it waits for in-flight
+// batches to complete instead of rejecting the message, admitting it
+// once nothing else is held so an oversized line cannot block forever.
+func (t *transport) reserve(size int) bool {
+	limit := t.options.MaxMemoryBytes
+	if limit <= 0 {
+		return true
+	}
+
+	for t.memoryUsed()+size > limit {
+		if !t.options.BlockOnMemoryLimit {
+			return false
+		}
+		if t.memoryUsed() == 0 {
+			return true
+		}
+
+		// buffered lines only release memory once they are sent
 		t.flushSend()
+		t.released.Wait()
+	}
+
+	return true
+}
+
+func (t *transport) memoryUsed() int {
+	return t.bufferBytes + t.inflightBytes
+}
+
+func (t *transport) memoryUsage() MemoryUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return MemoryUsage{
+		Used:    t.memoryUsed(),
+		Limit:   t.options.MaxMemoryBytes,
+		Dropped: t.dropped,
+	}
+}
+
+func (t *transport) settings() BatchSettings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return BatchSettings{
+		FlushInterval: t.flushInterval,
+		MaxBufferLen:  t.maxBufferLen,
+		MaxBatchBytes: t.options.MaxBatchBytes,
+	}
+}
+
+// adapt tunes the effective batching settings when AdaptiveBatching is
+// enabled. A batch filling up before the interval elapses grows both the
+// batch size and the interval, while mostly-empty batches at the end of
+// an interval shrink them back towards the configured values.
+func (t *transport) adapt(full bool) {
+	if !t.options.AdaptiveBatching {
+		return
+	}
+
+	if full {
+		t.maxBufferLen *= 2
+		if t.maxBufferLen > maxAdaptiveBufferLen {
+			t.maxBufferLen = maxAdaptiveBufferLen
+		}
+		t.flushInterval *= 2
+		if t.flushInterval > t.options.MaxFlushInterval {
+			t.flushInterval = t.options.MaxFlushInterval
+		}
+		return
+	}
+
+	largest := 0
+	for _, b := range t.batches {
+		if len(b.msgs) > largest {
+			largest = len(b.msgs)
+		}
+	}
+
+	if largest < t.maxBufferLen/4 {
+		t.maxBufferLen /= 2
+		if t.maxBufferLen < t.options.MaxBufferLen {
+			t.maxBufferLen = t.options.MaxBufferLen
+		}
+		t.flushInterval /= 2
+		if t.flushInterval < t.options.FlushInterval {
+			t.flushInterval = t.options.FlushInterval
+		}
 	}
 }
 
@@ -57,14 +264,67 @@ func (t *transport) flush() {
 	t.flushSend()
 }
 
-func (t *transport) flushInterval() {
-	ticker := time.NewTicker(t.options.FlushInterval)
-	defer ticker.Stop()
+// flushWait sends all buffered messages and waits up to timeout for every
+// in-flight batch to complete, reporting whether they did.
+func (t *transport) flushWait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		t.mu.Lock()
+		t.released.Broadcast()
+		t.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.flushSend()
+	for t.inflightBytes > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		t.released.Wait()
+	}
+
+	if t.archive != nil {
+		t.archive.close()
+	}
+	return true
+}
+
+func (t *transport) flushTick() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.adapt(false)
+	t.flushSend()
+	t.reclaim()
+	if t.archive != nil {
+		t.archive.rotateIdle()
+	}
+	return t.flushInterval
+}
+
+// reclaim removes batches which have not received a message within
+// Options.RouteIdleTimeout, so that routes which are no longer used
+// do not accumulate.
+func (t *transport) reclaim() {
+	cutoff := time.Now().Add(-t.options.RouteIdleTimeout)
+	for bk, b := range t.batches {
+		if len(b.msgs) == 0 && b.lastUsed.Before(cutoff) {
+			delete(t.batches, bk)
+		}
+	}
+}
+
+func (t *transport) flushLoop(interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			t.flush()
+		case <-timer.C:
+			timer.Reset(t.flushTick())
 		case <-t.done:
 			return
 		}
@@ -72,29 +332,55 @@ func (t *transport) flushInterval() {
 }
 
 func (t *transport) flushSend() {
-	msgs := t.buffer
-	t.buffer = t.buffer[:0]
+	for bk, b := range t.batches {
+		t.flushBatch(bk, b)
+	}
+}
+
+func (t *transport) flushBatch(bk batchKey, b *batch) {
+	msgs := b.msgs
+	size := b.bytes
+	b.msgs = nil
+	b.bytes = 0
 
 	if len(msgs) == 0 {
 		return
 	}
 
+	t.bufferBytes -= size
+	t.inflightBytes += size
 	t.wg.Add(1)
 	go func() {
 		// TODO(mdeltito): in the future a retry should be triggered
 		// with the msgs pulled out of the buffer
-		t.send(msgs)
+		t.send(bk, msgs)
+
+		t.mu.Lock()
+		t.inflightBytes -= size
+		t.released.Broadcast()
+		t.mu.Unlock()
+
 		t.wg.Done()
 	}()
 }
 
-func (t *transport) send(msgs []Message) error {
+func (t *transport) send(bk batchKey, msgs []Message) error {
+	payload := t.payload(bk, msgs)
+	if t.archive != nil {
+		return t.archive.write(payload)
+	}
+
+	return post(payload, t.options)
+}
+
+func (t *transport) payload(bk batchKey, msgs []Message) Payload {
 	var lines []Line
 	for _, msg := range msgs {
 		line := Line{
 			Body:  msg.Body,
 			App:   msg.Options.App,
 			Env:   msg.Options.Env,
+			File:  msg.file,
 			Level: msg.Options.Level,
 		}
 
@@ -104,36 +390,69 @@ func (t *transport) send(msgs []Message) error {
 		}
 		line.Timestamp = timestamp.UnixNano() / int64(time.Millisecond)
 
-		if msg.Options.Meta != "" {
+		if msg.Options.Meta != "" || len(msg.Options.Fields) > 0 {
 			line.Meta = metaEnvelope{
 				indexed: msg.Options.IndexMeta,
 				meta:    msg.Options.Meta,
+				fields:  msg.Options.Fields,
 			}
 		}
 
 		lines = append(lines, line)
 	}
 
-	payload := Payload{
-		APIKey:     t.key,
-		Hostname:   t.options.Hostname,
-		IPAddress:  t.options.IPAddress,
-		MacAddress: t.options.MacAddress,
-		Tags:       t.options.Tags,
+	return Payload{
+		APIKey:     bk.key,
+		Hostname:   bk.hostname,
+		IPAddress:  bk.ipAddress,
+		MacAddress: bk.macAddress,
+		Tags:       bk.tags,
 		Lines:      lines,
 	}
+}
 
+// post sends the payload to Options.IngestURL.
+func post(payload Payload, options Options) error {
 	pbytes, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", t.options.IngestURL, bytes.NewBuffer(pbytes))
+	return postBytes(pbytes, payload.APIKey, options)
+}
+
+// ingestError is returned when the ingestion endpoint rejects a request.
+type ingestError struct {
+	status int
+}
+
+func (e *ingestError) Error() string {
+	if e.status >= 500 {
+		return fmt.Sprintf("Server error: %d", e.status)
+	}
+	return fmt.Sprintf("Request rejected: %d", e.status)
+}
+
+// retryable reports whether a failed request may succeed when sent again,
+// which is the case for network errors, server errors and rate limiting.
+func retryable(err error) bool {
+	var ie *ingestError
+	if errors.As(err, &ie) {
+		return ie.status >= 500 || ie.status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func postBytes(pbytes []byte, key string, options Options) error {
+	req, err := http.NewRequest("POST", options.IngestURL, bytes.NewBuffer(pbytes))
+	if err != nil {
+		return err
+	}
 	req.Header.Set("user-agent", os.Getenv("USERAGENT"))
-	req.Header.Set("apikey", t.key)
+	req.Header.Set("apikey", key)
 	req.Header.Set("Content-type", "application/json")
 
-	client := &http.Client{Timeout: t.options.SendTimeout}
+	client := &http.Client{Timeout: options.SendTimeout}
 	resp, err := client.Do(req)
 
 	if err != nil {
@@ -141,8 +460,8 @@ func (t *transport) send(msgs []Message) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 500 {
-		return fmt.Errorf("Server error: %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &ingestError{status: resp.StatusCode}
 	}
 
 	var apiresp ingestAPIResponse